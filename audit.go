@@ -0,0 +1,95 @@
+/******************************************************************************
+*
+*  Copyright 2024 SAP SE
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync/atomic"
+
+	"github.com/urfave/cli/v2"
+)
+
+func audit(ctx *cli.Context) (err error) {
+	required := ctx.StringSlice("require")
+	client, err := createClient(ctx)
+	if err != nil {
+		return err
+	}
+	out, err := openOutput(ctx)
+	if err != nil {
+		return err
+	}
+	defer closeOutput(out, &err)
+	lister := newLister(ctx, client)
+	var checked atomic.Int64
+	if ctx.Bool("progress") {
+		stop := reportProgress(func() {
+			lister.printProgress()
+			fmt.Fprintf(os.Stderr, "checked %d secrets\n", checked.Load())
+		})
+		defer stop()
+	}
+	paths, err := lister.listSecretDirRecurse(ctx.Context, "/")
+	if err != nil {
+		return err
+	}
+	for idx, path := range paths {
+		paths[idx] = relativePath(path)
+	}
+	sort.Strings(paths)
+
+	kv := client.KVv2(ctx.String("mount"))
+	result := mapConcurrently(ctx.Context, paths, func(path string) ([]string, error) {
+		defer checked.Add(1)
+		meta, err := kv.GetMetadata(ctx.Context, path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get metadata for %s: %w", path, err)
+		}
+		missing := make([]string, 0)
+		for _, key := range required {
+			if _, ok := meta.CustomMetadata[key]; !ok {
+				missing = append(missing, key)
+			}
+		}
+		return missing, nil
+	})
+
+	violations := 0
+	errs := make([]error, 0)
+	for idx, r := range result {
+		if r.err != nil {
+			errs = append(errs, r.err)
+			continue
+		}
+		if len(r.value) > 0 {
+			violations++
+			fmt.Fprintf(out, "%s: missing %s\n", paths[idx], strings.Join(r.value, ", "))
+		}
+	}
+	if len(errs) > 0 {
+		return joinErrors(errs, len(paths)-len(errs))
+	}
+	if ctx.Bool("exit-code") && violations > 0 {
+		return errDifferencesFound
+	}
+	return nil
+}
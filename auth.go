@@ -0,0 +1,229 @@
+/******************************************************************************
+*
+*  Copyright 2024 SAP SE
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/urfave/cli/v2"
+)
+
+// authMethods are the supported values of --auth-method. "token" keeps the
+// token from VAULT_TOKEN or ~/.vault-token.
+var authMethods = []string{"token", "approle", "kubernetes", "oidc", "cert"}
+
+// oidcLoginTimeout limits how long the OIDC login waits for the browser.
+const oidcLoginTimeout = 5 * time.Minute
+
+// loginToken caches the token obtained with --auth-method, so that commands
+// creating several clients only log in once.
+var loginToken struct {
+	sync.Mutex
+	token string
+}
+
+// newLoginClient creates a client without a token for logging in with an
+// auth method.
+func newLoginClient() (*api.Client, error) {
+	config := api.DefaultConfig()
+	if config.Error != nil {
+		return nil, fmt.Errorf("while reading Vault config from environment: %w", config.Error)
+	}
+	client, err := api.NewClient(config)
+	if err != nil {
+		return nil, fmt.Errorf("while initializing Vault client: %w", err)
+	}
+	// the token from the environment is replaced by the login
+	client.ClearToken()
+	return client, nil
+}
+
+// login authenticates client with the method given by --auth-method and sets
+// the resulting token.
+func login(ctx *cli.Context, client *api.Client) error {
+	loginToken.Lock()
+	defer loginToken.Unlock()
+	if loginToken.token != "" {
+		client.SetToken(loginToken.token)
+		return nil
+	}
+	method := ctx.String("auth-method")
+	mount := ctx.String("auth-mount")
+	if mount == "" {
+		mount = method
+	}
+	mount = strings.Trim(mount, "/")
+	role := ctx.String("role")
+
+	var secret *api.Secret
+	var err error
+	switch method {
+	case "approle":
+		roleID := ctx.String("role-id")
+		if roleID == "" {
+			return errors.New("--role-id or VAULT_ROLE_ID is required for --auth-method=approle")
+		}
+		secretID := os.Getenv("VAULT_SECRET_ID")
+		if path := ctx.String("secret-id-file"); path != "" {
+			content, err := os.ReadFile(path)
+			if err != nil {
+				return fmt.Errorf("failed to read secret ID: %w", err)
+			}
+			secretID = strings.TrimSpace(string(content))
+		}
+		secret, err = client.Logical().WriteWithContext(ctx.Context, fmt.Sprintf("auth/%s/login", mount), map[string]any{
+			"role_id":   roleID,
+			"secret_id": secretID,
+		})
+	case "kubernetes":
+		if role == "" {
+			return errors.New("--role is required for --auth-method=kubernetes")
+		}
+		jwt, readErr := os.ReadFile(ctx.String("jwt-path"))
+		if readErr != nil {
+			return fmt.Errorf("failed to read service account token: %w", readErr)
+		}
+		secret, err = client.Logical().WriteWithContext(ctx.Context, fmt.Sprintf("auth/%s/login", mount), map[string]any{
+			"role": role,
+			"jwt":  strings.TrimSpace(string(jwt)),
+		})
+	case "cert":
+		if ctx.String("client-cert") == "" || ctx.String("client-key") == "" {
+			return errors.New("--client-cert and --client-key are required for --auth-method=cert")
+		}
+		// without a role, vault tries all roles matching the certificate
+		secret, err = client.Logical().WriteWithContext(ctx.Context, fmt.Sprintf("auth/%s/login", mount), map[string]any{
+			"name": role,
+		})
+	case "oidc":
+		secret, err = oidcLogin(ctx, client, mount, role)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to log in with %s: %w", method, err)
+	}
+	if secret == nil || secret.Auth == nil || secret.Auth.ClientToken == "" {
+		return fmt.Errorf("failed to log in with %s: no token was returned", method)
+	}
+	loginToken.token = secret.Auth.ClientToken
+	client.SetToken(loginToken.token)
+	return nil
+}
+
+// oidcLogin runs the OIDC authorization code flow in the browser and
+// receives the callback on a local listener, like `vault login -method=oidc`.
+func oidcLogin(ctx *cli.Context, client *api.Client, mount, role string) (*api.Secret, error) {
+	address := ctx.String("oidc-listen-address")
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen for the OIDC callback: %w", err)
+	}
+	defer listener.Close()
+	nonceBytes := make([]byte, 20)
+	if _, err := rand.Read(nonceBytes); err != nil {
+		return nil, err
+	}
+	nonce := hex.EncodeToString(nonceBytes)
+	redirectURI := fmt.Sprintf("http://%s/oidc/callback", address)
+	resp, err := client.Logical().WriteWithContext(ctx.Context, fmt.Sprintf("auth/%s/oidc/auth_url", mount), map[string]any{
+		"role":         role,
+		"redirect_uri": redirectURI,
+		"client_nonce": nonce,
+	})
+	if err != nil {
+		return nil, err
+	}
+	authURL := ""
+	if resp != nil {
+		authURL, _ = resp.Data["auth_url"].(string)
+	}
+	if authURL == "" {
+		return nil, fmt.Errorf("no auth URL was returned, check that the role allows the redirect URI %s", redirectURI)
+	}
+
+	callbacks := make(chan url.Values, 1)
+	server := &http.Server{
+		ReadHeaderTimeout: 10 * time.Second,
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/oidc/callback" {
+				http.NotFound(w, r)
+				return
+			}
+			select {
+			case callbacks <- r.URL.Query():
+			default:
+			}
+			fmt.Fprintln(w, "Login received, you can close this window and return to mutavault.")
+		}),
+	}
+	go server.Serve(listener) //nolint:errcheck // fails with ErrServerClosed on return
+	defer server.Close()
+
+	fmt.Fprintf(os.Stderr, "Complete the login in your browser, if it does not open visit:\n\n    %s\n\n", authURL)
+	openBrowser(authURL)
+
+	var query url.Values
+	select {
+	case query = <-callbacks:
+	case <-ctx.Context.Done():
+		return nil, ctx.Context.Err()
+	case <-time.After(oidcLoginTimeout):
+		return nil, fmt.Errorf("no OIDC callback was received within %s", oidcLoginTimeout)
+	}
+	if msg := query.Get("error"); msg != "" {
+		return nil, fmt.Errorf("OIDC provider returned %s: %s", msg, query.Get("error_description"))
+	}
+	return client.Logical().ReadWithDataWithContext(ctx.Context, fmt.Sprintf("auth/%s/oidc/callback", mount), map[string][]string{
+		"state":        {query.Get("state")},
+		"code":         {query.Get("code")},
+		"id_token":     {query.Get("id_token")},
+		"client_nonce": {nonce},
+	})
+}
+
+// openBrowser tries to open target in the default browser of the desktop.
+func openBrowser(target string) {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", target)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", target)
+	default:
+		cmd = exec.Command("xdg-open", target)
+	}
+	if err := cmd.Start(); err != nil {
+		slog.Debug("failed to open browser", "error", err)
+		return
+	}
+	go cmd.Wait() //nolint:errcheck // the browser outlives the opener
+}
@@ -0,0 +1,168 @@
+/******************************************************************************
+*
+*  Copyright 2024 SAP SE
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"text/tabwriter"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/urfave/cli/v2"
+)
+
+// pathCapabilities are the operations the current token may perform on a
+// secret, as reported by kv capabilities.
+type pathCapabilities struct {
+	Path   string `json:"path"   yaml:"path"`
+	Read   bool   `json:"read"   yaml:"read"`
+	List   bool   `json:"list"   yaml:"list"`
+	Update bool   `json:"update" yaml:"update"`
+	Delete bool   `json:"delete" yaml:"delete"`
+}
+
+// apiPaths returns the API paths which govern the data and the listing of
+// path. For kvv1 engines both are the same.
+func apiPaths(mount string, kvVersion int, path string) (dataPath, listPath string) {
+	if kvVersion == 1 {
+		return fmt.Sprintf("%s/%s", mount, path), fmt.Sprintf("%s/%s", mount, path)
+	}
+	return fmt.Sprintf("%s/data/%s", mount, path), fmt.Sprintf("%s/metadata/%s", mount, path)
+}
+
+// hasCapability reports whether the capabilities returned by Vault grant
+// capability. A deny overrides everything else.
+func hasCapability(capabilities []string, capability string) bool {
+	if slices.Contains(capabilities, "deny") {
+		return false
+	}
+	return slices.Contains(capabilities, "root") || slices.Contains(capabilities, capability)
+}
+
+// capabilitiesSelf queries sys/capabilities-self for all given API paths in a
+// single request.
+func capabilitiesSelf(ctx *cli.Context, client *api.Client, apiPaths []string) (map[string][]string, error) {
+	secret, err := client.Logical().WriteWithContext(ctx.Context, "sys/capabilities-self", map[string]any{
+		"paths": apiPaths,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if secret == nil || secret.Data == nil {
+		return nil, errors.New("empty response from sys/capabilities-self")
+	}
+	result := make(map[string][]string, len(apiPaths))
+	for _, path := range apiPaths {
+		raw, ok := secret.Data[path].([]any)
+		if !ok {
+			return nil, fmt.Errorf("no capabilities were returned for %s", path)
+		}
+		capabilities := make([]string, 0, len(raw))
+		for _, c := range raw {
+			if s, ok := c.(string); ok {
+				capabilities = append(capabilities, s)
+			}
+		}
+		result[path] = capabilities
+	}
+	return result, nil
+}
+
+func capabilities(ctx *cli.Context) (err error) {
+	format := ctx.String("format")
+	if err := checkFormat(format, "text", "json", "yaml"); err != nil {
+		return err
+	}
+	batchSize := ctx.Int("batch-size")
+	if batchSize < 1 {
+		return errors.New("--batch-size must be at least 1")
+	}
+	client, err := createClient(ctx)
+	if err != nil {
+		return err
+	}
+	out, err := openOutput(ctx)
+	if err != nil {
+		return err
+	}
+	defer closeOutput(out, &err)
+	paths, err := readPaths(ctx)
+	if err != nil {
+		return err
+	}
+	if len(paths) == 0 {
+		return errors.New("no paths were given")
+	}
+	mount, kvVersion := ctx.String("mount"), ctx.Int("kv-version")
+
+	batches := make([][]string, 0, len(paths)/batchSize+1)
+	for start := 0; start < len(paths); start += batchSize {
+		batches = append(batches, paths[start:min(start+batchSize, len(paths))])
+	}
+	result := mapConcurrently(ctx.Context, batches, func(batch []string) ([]pathCapabilities, error) {
+		query := make([]string, 0, 2*len(batch))
+		for _, path := range batch {
+			dataPath, listPath := apiPaths(mount, kvVersion, path)
+			query = append(query, dataPath)
+			if listPath != dataPath {
+				query = append(query, listPath)
+			}
+		}
+		granted, err := capabilitiesSelf(ctx, client, query)
+		if err != nil {
+			return nil, err
+		}
+		caps := make([]pathCapabilities, 0, len(batch))
+		for _, path := range batch {
+			dataPath, listPath := apiPaths(mount, kvVersion, path)
+			caps = append(caps, pathCapabilities{
+				Path:   path,
+				Read:   hasCapability(granted[dataPath], "read"),
+				List:   hasCapability(granted[listPath], "list"),
+				Update: hasCapability(granted[dataPath], "update"),
+				Delete: hasCapability(granted[dataPath], "delete"),
+			})
+		}
+		return caps, nil
+	})
+	all := make([]pathCapabilities, 0, len(paths))
+	for _, r := range result {
+		if r.err != nil {
+			return fmt.Errorf("failed to query capabilities: %w", r.err)
+		}
+		all = append(all, r.value...)
+	}
+
+	if format != "text" {
+		return encodeOutput(out, format, all)
+	}
+	yesNo := func(b bool) string {
+		if b {
+			return "yes"
+		}
+		return "no"
+	}
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PATH\tREAD\tLIST\tUPDATE\tDELETE")
+	for _, c := range all {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", c.Path, yesNo(c.Read), yesNo(c.List), yesNo(c.Update), yesNo(c.Delete))
+	}
+	return w.Flush()
+}
@@ -0,0 +1,84 @@
+/******************************************************************************
+*
+*  Copyright 2024 SAP SE
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+)
+
+// checkpoint persists the list responses of a traversal, so that an
+// interrupted listall can resume without listing the visited directories again.
+type checkpoint struct {
+	file  string
+	mutex sync.Mutex
+	// keyed by mount and directory, e.g. "secrets/team/"
+	listings map[string][]string
+}
+
+// loadCheckpoint reads the checkpoint file if it exists or starts a new one.
+func loadCheckpoint(file string) (*checkpoint, error) {
+	c := &checkpoint{file: file, listings: make(map[string][]string)}
+	buf, err := os.ReadFile(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint: %w", err)
+	}
+	if err := json.Unmarshal(buf, &c.listings); err != nil {
+		return nil, fmt.Errorf("failed to parse checkpoint %s: %w", file, err)
+	}
+	slog.Info("resuming from checkpoint", "file", file, "directories", len(c.listings))
+	return c, nil
+}
+
+func (c *checkpoint) lookup(mount, dir string) ([]string, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	keys, ok := c.listings[mount+dir]
+	return keys, ok
+}
+
+func (c *checkpoint) record(mount, dir string, keys []string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.listings[mount+dir] = keys
+}
+
+// save atomically replaces the checkpoint file with the current state.
+func (c *checkpoint) save() {
+	c.mutex.Lock()
+	buf, err := json.Marshal(c.listings)
+	c.mutex.Unlock()
+	if err == nil {
+		tmpFile := c.file + ".tmp"
+		err = os.WriteFile(tmpFile, buf, 0o600)
+		if err == nil {
+			err = os.Rename(tmpFile, c.file)
+		}
+	}
+	if err != nil {
+		slog.Warn("failed to save checkpoint", "file", c.file, "error", err)
+	}
+}
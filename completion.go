@@ -0,0 +1,87 @@
+/******************************************************************************
+*
+*  Copyright 2024 SAP SE
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/urfave/cli/v2"
+)
+
+// completePaths completes the last argument to the paths of secrets and
+// directories in the kv engine. This requires the completion script to pass the
+// word being completed as last argument. Nothing is printed if vault is not
+// reachable or no token is available.
+func completePaths(ctx *cli.Context) {
+	partial := ""
+	if ctx.NArg() > 0 {
+		partial = ctx.Args().Get(ctx.NArg() - 1)
+	}
+	if strings.HasPrefix(partial, "-") {
+		cli.DefaultCompleteWithFlags(ctx.Command)(ctx)
+		return
+	}
+	client, err := createCompletionClient(ctx)
+	if err != nil {
+		return
+	}
+	dir := partial[:strings.LastIndex(partial, "/")+1]
+	keys, err := newLister(ctx, client).listSecretDir(ctx.Context, "/"+dir)
+	if err != nil {
+		return
+	}
+	for _, key := range keys {
+		if strings.HasPrefix(dir+key, partial) {
+			fmt.Println(dir + key)
+		}
+	}
+}
+
+// createCompletionClient creates a client with the token from VAULT_TOKEN or
+// ~/.vault-token. Unlike createClient, it never logs in with --auth-method and
+// does not renew the token, since an interactive login like oidc would block
+// the shell during completion.
+func createCompletionClient(ctx *cli.Context) (*api.Client, error) {
+	token := os.Getenv("VAULT_TOKEN")
+	if token == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return nil, err
+		}
+		content, err := os.ReadFile(filepath.Join(homeDir, ".vault-token"))
+		if err != nil {
+			return nil, err
+		}
+		token = strings.TrimSpace(string(content))
+	}
+	if token == "" {
+		return nil, errors.New("no token available")
+	}
+	client, err := newLoginClient()
+	if err != nil {
+		return nil, err
+	}
+	client.SetToken(token)
+	return configureClient(ctx, client)
+}
@@ -0,0 +1,63 @@
+/******************************************************************************
+*
+*  Copyright 2024 SAP SE
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+)
+
+// yesFlag skips the confirmation of destructive commands.
+func yesFlag() cli.Flag {
+	return &cli.BoolFlag{
+		Name:    "yes",
+		Aliases: []string{"y"},
+		Usage:   "Do not ask for confirmation, required when not running in a terminal",
+	}
+}
+
+// confirm asks the user on the terminal whether the operation described by
+// action should be applied to count secrets, unless --yes is set. The terminal
+// is used instead of stdin because some commands read their input from stdin.
+func confirm(ctx *cli.Context, action string, count int) error {
+	if ctx.Bool("yes") {
+		return nil
+	}
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return fmt.Errorf("refusing to %s %d secret(s) without confirmation outside of a terminal, pass --yes", action, count)
+	}
+	defer tty.Close()
+	fmt.Fprintf(tty, "This will %s %d secret(s). Continue? [y/N] ", action, count)
+	answer, err := bufio.NewReader(tty).ReadString('\n')
+	if err != nil {
+		return fmt.Errorf("failed to read confirmation: %w", err)
+	}
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return nil
+	default:
+		return errors.New("aborted")
+	}
+}
@@ -0,0 +1,255 @@
+/******************************************************************************
+*
+*  Copyright 2024 SAP SE
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/urfave/cli/v2"
+)
+
+func copySecret(ctx *cli.Context) error {
+	if ctx.NArg() != 2 {
+		return errors.New("expected exactly two arguments: source and destination path")
+	}
+	client, err := createClient(ctx)
+	if err != nil {
+		return err
+	}
+	c := newCopier(ctx, client)
+	pairs, err := c.pairs(ctx)
+	if err != nil {
+		return err
+	}
+	result := mapConcurrently(ctx.Context, pairs, func(pair copyPair) (struct{}, error) {
+		_, err := c.copy(ctx.Context, pair.src, pair.dst)
+		return struct{}{}, err
+	})
+	errs := make([]error, 0)
+	for idx, r := range result {
+		if r.err != nil {
+			errs = append(errs, r.err)
+			continue
+		}
+		if ctx.Bool("recursive") {
+			fmt.Printf("%s -> %s\n", pairs[idx].src, pairs[idx].dst)
+		}
+	}
+	return joinErrors(errs, len(result)-len(errs))
+}
+
+func moveSecret(ctx *cli.Context) error {
+	if ctx.NArg() != 2 {
+		return errors.New("expected exactly two arguments: source and destination path")
+	}
+	client, err := createClient(ctx)
+	if err != nil {
+		return err
+	}
+	c := newCopier(ctx, client)
+	pairs, err := c.pairs(ctx)
+	if err != nil {
+		return err
+	}
+	// moving a secret onto itself would delete the only copy
+	if c.srcMount == c.dstMount {
+		for _, pair := range pairs {
+			if strings.Trim(pair.src, "/") == strings.Trim(pair.dst, "/") {
+				return fmt.Errorf("cannot move %s onto itself", pair.src)
+			}
+		}
+	}
+	if err := confirm(ctx, "move", len(pairs)); err != nil {
+		return err
+	}
+	softDelete := ctx.Bool("soft-delete")
+	result := mapConcurrently(ctx.Context, pairs, func(pair copyPair) (struct{}, error) {
+		return struct{}{}, c.move(ctx.Context, pair.src, pair.dst, softDelete)
+	})
+	errs := make([]error, 0)
+	for idx, r := range result {
+		if r.err != nil {
+			errs = append(errs, r.err)
+			continue
+		}
+		if ctx.Bool("recursive") {
+			fmt.Printf("%s -> %s\n", pairs[idx].src, pairs[idx].dst)
+		}
+	}
+	return joinErrors(errs, len(result)-len(errs))
+}
+
+// copier copies secrets for copy and move.
+type copier struct {
+	client   *api.Client
+	srcMount string
+	dstMount string
+	// if set, an existing destination gets new versions instead of being an error
+	overwrite bool
+	// if set, all readable versions are copied instead of only the latest one
+	allVersions bool
+}
+
+func newCopier(ctx *cli.Context, client *api.Client) *copier {
+	c := &copier{
+		client:      client,
+		srcMount:    ctx.String("mount"),
+		dstMount:    ctx.String("dst-mount"),
+		overwrite:   ctx.Bool("overwrite"),
+		allVersions: ctx.Bool("all-versions"),
+	}
+	if srcMount := ctx.String("src-mount"); srcMount != "" {
+		c.srcMount = srcMount
+	}
+	if c.dstMount == "" {
+		c.dstMount = c.srcMount
+	}
+	return c
+}
+
+// copyPair is a source path and the destination path it is copied to.
+type copyPair struct {
+	src string
+	dst string
+}
+
+// pairs returns the paths to copy given by the arguments. With --recursive,
+// the arguments are directories and all secrets below the source are copied
+// to the same relative path below the destination.
+func (c *copier) pairs(ctx *cli.Context) ([]copyPair, error) {
+	src, dst := cleanPath(ctx.Args().Get(0)), cleanPath(ctx.Args().Get(1))
+	if !ctx.Bool("recursive") {
+		return []copyPair{{src: src, dst: dst}}, nil
+	}
+	lister := newLister(ctx, c.client)
+	lister.mount = c.srcMount
+	srcDir := normalizeDir(src)
+	paths, err := lister.listSecretDirRecurse(ctx.Context, srcDir)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+	dstDir := relativePath(normalizeDir(dst))
+	pairs := make([]copyPair, 0, len(paths))
+	for _, path := range paths {
+		pairs = append(pairs, copyPair{src: relativePath(path), dst: dstDir + strings.TrimPrefix(path, srcDir)})
+	}
+	return pairs, nil
+}
+
+// copy copies the latest version or all versions and the custom metadata of
+// a secret and returns the latest copied version.
+func (c *copier) copy(ctx context.Context, src, dst string) (*api.KVSecret, error) {
+	srcKV, dstKV := c.client.KVv2(c.srcMount), c.client.KVv2(c.dstMount)
+	meta, err := srcKV.GetMetadata(ctx, src)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get metadata for %s: %w", src, err)
+	}
+	if !c.overwrite {
+		exists, err := secretExists(ctx, c.client, c.dstMount, dst)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			return nil, fmt.Errorf("secret on path %s already exists, pass --overwrite to replace it", dst)
+		}
+	}
+	versions := []int{meta.CurrentVersion}
+	if c.allVersions {
+		versions = make([]int, 0, len(meta.Versions))
+		for _, v := range meta.Versions {
+			versions = append(versions, v.Version)
+		}
+		sort.Ints(versions)
+	}
+	var secret *api.KVSecret
+	for _, version := range versions {
+		current, err := srcKV.GetVersion(ctx, src, version)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read version %d of secret %s: %w", version, src, err)
+		}
+		// deleted and destroyed versions only come with their metadata
+		if current.Data == nil {
+			slog.Warn("skipping version without data", "path", src, "version", version)
+			continue
+		}
+		if _, err = dstKV.Put(ctx, dst, current.Data); err != nil {
+			return nil, fmt.Errorf("failed to write version %d of secret %s to %s: %w", version, src, dst, err)
+		}
+		secret = current
+	}
+	if secret == nil {
+		return nil, fmt.Errorf("secret %s has no version with data to copy", src)
+	}
+	err = dstKV.PutMetadata(ctx, dst, api.KVMetadataPutInput{
+		CASRequired:        meta.CASRequired,
+		CustomMetadata:     meta.CustomMetadata,
+		DeleteVersionAfter: meta.DeleteVersionAfter,
+		MaxVersions:        meta.MaxVersions,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to update metadata for %s: %w", dst, err)
+	}
+	return secret, nil
+}
+
+// move copies a secret and removes the source once the copy has been
+// verified, either completely or by soft-deleting its latest version.
+func (c *copier) move(ctx context.Context, src, dst string, softDelete bool) error {
+	secret, err := c.copy(ctx, src, dst)
+	if err != nil {
+		return err
+	}
+	// only delete the source once the destination is known to be intact
+	written, err := c.client.KVv2(c.dstMount).Get(ctx, dst)
+	if err != nil {
+		return fmt.Errorf("failed to verify secret %s: %w", dst, err)
+	}
+	if !reflect.DeepEqual(written.Data, secret.Data) {
+		return fmt.Errorf("secret %s does not contain the copied data, keeping %s", dst, src)
+	}
+	if softDelete {
+		err = c.client.KVv2(c.srcMount).Delete(ctx, src)
+	} else {
+		err = c.client.KVv2(c.srcMount).DeleteMetadata(ctx, src)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete secret %s: %w", src, err)
+	}
+	return nil
+}
+
+// secretExists reports whether metadata for the given path exists in the mount.
+func secretExists(ctx context.Context, client *api.Client, mount, path string) (bool, error) {
+	meta, err := client.KVv2(mount).GetMetadata(ctx, path)
+	if errors.Is(err, api.ErrSecretNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to get metadata for %s: %w", path, err)
+	}
+	return meta != nil, nil
+}
@@ -0,0 +1,125 @@
+/******************************************************************************
+*
+*  Copyright 2024 SAP SE
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+)
+
+// writeCustomMetasCSV writes one row per object with a column for each key of
+// any object. The path key comes first, keys missing in an object are left
+// empty and values which are not strings are JSON-encoded.
+func writeCustomMetasCSV(w io.Writer, pathKey string, customMetas []map[string]any) error {
+	keySet := make(map[string]bool)
+	for _, customMeta := range customMetas {
+		for key := range customMeta {
+			if key != pathKey {
+				keySet[key] = true
+			}
+		}
+	}
+	keys := make([]string, 0, len(keySet))
+	for key := range keySet {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	keys = append([]string{pathKey}, keys...)
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(keys); err != nil {
+		return err
+	}
+	for _, customMeta := range customMetas {
+		row := make([]string, len(keys))
+		for idx, key := range keys {
+			value, ok := customMeta[key]
+			if !ok {
+				continue
+			}
+			if str, ok := value.(string); ok {
+				row[idx] = str
+				continue
+			}
+			buf, err := json.Marshal(value)
+			if err != nil {
+				return fmt.Errorf("failed to encode %s of %v: %w", key, customMeta[pathKey], err)
+			}
+			row[idx] = string(buf)
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// readCustomMetasCSV reads rows as written by writeCustomMetasCSV into one
+// object per row. Empty cells are left out of the object, and the settings
+// max_versions and cas_required are parsed into their types so that they
+// are validated like in JSON input.
+func readCustomMetasCSV(r io.Reader) ([]any, error) {
+	reader := csv.NewReader(r)
+	header, err := reader.Read()
+	if err == io.EOF {
+		return []any{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	result := make([]any, 0)
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			return result, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		object := make(map[string]any, len(header))
+		for idx, key := range header {
+			if row[idx] == "" {
+				continue
+			}
+			object[key] = parseCSVSetting(key, row[idx])
+		}
+		result = append(result, object)
+	}
+}
+
+// parseCSVSetting converts the cells of the metadata settings to the types
+// expected by extractMetadataSettings, other cells stay strings.
+func parseCSVSetting(key, cell string) any {
+	switch key {
+	case "max_versions":
+		if number, err := strconv.Atoi(cell); err == nil {
+			return number
+		}
+	case "cas_required":
+		if value, err := strconv.ParseBool(cell); err == nil {
+			return value
+		}
+	}
+	return cell
+}
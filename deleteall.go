@@ -0,0 +1,134 @@
+/******************************************************************************
+*
+*  Copyright 2024 SAP SE
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/urfave/cli/v2"
+)
+
+func deleteAll(ctx *cli.Context) error {
+	destroyVersions, deleteMetadata := ctx.Bool("destroy"), ctx.Bool("metadata")
+	if destroyVersions && deleteMetadata {
+		return errors.New("--destroy and --metadata are mutually exclusive")
+	}
+	verb, op := "deleted", deleteLatestVersion
+	switch {
+	case destroyVersions:
+		verb, op = "destroyed", destroyAllVersions
+	case deleteMetadata:
+		verb, op = "removed", (*api.KVv2).DeleteMetadata
+	}
+	dryRun := ctx.Bool("dry-run")
+	if !dryRun && !ctx.Bool("yes") {
+		return errors.New("refusing to delete secrets without --yes, pass --dry-run to see what would be deleted")
+	}
+	client, err := createClient(ctx)
+	if err != nil {
+		return err
+	}
+	paths, err := deleteAllPaths(ctx, client)
+	if err != nil {
+		return err
+	}
+	if dryRun {
+		for _, path := range paths {
+			fmt.Printf("%s: would be %s\n", path, verb)
+		}
+		return nil
+	}
+
+	kv := client.KVv2(ctx.String("mount"))
+	result := mapConcurrently(ctx.Context, paths, func(path string) (struct{}, error) {
+		if err := op(kv, ctx.Context, path); err != nil {
+			return struct{}{}, fmt.Errorf("failed to delete secret %s: %w", path, err)
+		}
+		return struct{}{}, nil
+	})
+	errs := make([]error, 0)
+	for idx, r := range result {
+		if r.err != nil {
+			errs = append(errs, r.err)
+			continue
+		}
+		fmt.Printf("%s: %s\n", paths[idx], verb)
+	}
+	return joinErrors(errs, len(result)-len(errs))
+}
+
+// deleteAllPaths returns the sorted paths selected by the glob patterns in the
+// arguments or read from stdin.
+func deleteAllPaths(ctx *cli.Context, client *api.Client) ([]string, error) {
+	if ctx.Bool("stdin") {
+		return readPaths(ctx)
+	}
+	if ctx.NArg() == 0 {
+		return nil, errors.New("expected at least one glob pattern or --stdin")
+	}
+	selected := make(map[string]bool)
+	for _, pattern := range pathArgs(ctx) {
+		lister := newLister(ctx, client)
+		var err error
+		lister.filter, err = newPathFilter(pattern, "")
+		if err != nil {
+			return nil, err
+		}
+		paths, err := lister.listSecretDirRecurse(ctx.Context, "/")
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range paths {
+			selected[relativePath(path)] = true
+		}
+	}
+	paths := make([]string, 0, len(selected))
+	for path := range selected {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+func deleteLatestVersion(kv *api.KVv2, ctx context.Context, path string) error {
+	return kv.Delete(ctx, path)
+}
+
+// destroyAllVersions permanently destroys all versions of a secret which have
+// not been destroyed yet, but keeps its metadata.
+func destroyAllVersions(kv *api.KVv2, ctx context.Context, path string) error {
+	meta, err := kv.GetMetadata(ctx, path)
+	if err != nil {
+		return err
+	}
+	versions := make([]int, 0, len(meta.Versions))
+	for _, v := range meta.Versions {
+		if !v.Destroyed {
+			versions = append(versions, v.Version)
+		}
+	}
+	if len(versions) == 0 {
+		return nil
+	}
+	return kv.Destroy(ctx, path, versions)
+}
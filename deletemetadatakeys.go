@@ -0,0 +1,95 @@
+/******************************************************************************
+*
+*  Copyright 2024 SAP SE
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/urfave/cli/v2"
+)
+
+func deleteMetadataKeys(ctx *cli.Context) error {
+	keys := ctx.StringSlice("key")
+	client, err := createClient(ctx)
+	if err != nil {
+		return err
+	}
+	paths := pathArgs(ctx)
+	if ctx.Bool("recursive") {
+		paths, err = listRecursive(ctx, client, paths)
+		if err != nil {
+			return err
+		}
+	}
+	kv := client.KVv2(ctx.String("mount"))
+	result := mapConcurrently(ctx.Context, paths, func(path string) ([]string, error) {
+		removed := make([]string, 0)
+		err := modifyCustomMeta(ctx.Context, kv, path, func(customMeta map[string]any) bool {
+			for _, key := range keys {
+				if _, ok := customMeta[key]; ok {
+					delete(customMeta, key)
+					removed = append(removed, key)
+				}
+			}
+			return len(removed) > 0
+		})
+		return removed, err
+	})
+
+	errs := make([]error, 0)
+	for idx, r := range result {
+		if r.err != nil {
+			errs = append(errs, r.err)
+			continue
+		}
+		if len(r.value) > 0 {
+			fmt.Printf("%s: removed %s\n", paths[idx], strings.Join(r.value, ", "))
+		}
+	}
+	return joinErrors(errs, len(result)-len(errs))
+}
+
+// modifyCustomMeta reads the custom metadata of a secret, lets modify change it
+// in place and writes it back with the other settings preserved if modify
+// reports a change.
+func modifyCustomMeta(ctx context.Context, kv *api.KVv2, path string, modify func(customMeta map[string]any) bool) error {
+	meta, err := kv.GetMetadata(ctx, path)
+	if err != nil {
+		return fmt.Errorf("failed to get metadata for %s: %w", path, err)
+	}
+	if meta.CustomMetadata == nil {
+		meta.CustomMetadata = make(map[string]any)
+	}
+	if !modify(meta.CustomMetadata) {
+		return nil
+	}
+	err = kv.PutMetadata(ctx, path, api.KVMetadataPutInput{
+		CASRequired:        meta.CASRequired,
+		CustomMetadata:     meta.CustomMetadata,
+		DeleteVersionAfter: meta.DeleteVersionAfter,
+		MaxVersions:        meta.MaxVersions,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update metadata for %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,210 @@
+/******************************************************************************
+*
+*  Copyright 2024 SAP SE
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+)
+
+// pathDetails is a path with its metadata as printed by listall --details.
+// Directories returned because of --max-depth only have a mount and path.
+type pathDetails struct {
+	Mount          string     `json:"mount"`
+	Path           string     `json:"path"`
+	CreatedTime    *time.Time `json:"created_time,omitempty"`
+	UpdatedTime    *time.Time `json:"updated_time,omitempty"`
+	CurrentVersion int        `json:"current_version,omitempty"`
+	Versions       int        `json:"versions,omitempty"`
+}
+
+// fetchDetails gets the metadata of all given paths. The details of the
+// paths that could be fetched are returned even if others failed.
+func fetchDetails(ctx context.Context, client *api.Client, paths []listedPath) ([]pathDetails, []error) {
+	result := mapConcurrently(ctx, paths, func(p listedPath) (pathDetails, error) {
+		details := pathDetails{Mount: p.Mount, Path: p.Path}
+		if strings.HasSuffix(p.Path, "/") {
+			return details, nil
+		}
+		meta, err := client.KVv2(p.Mount).GetMetadata(ctx, p.Path)
+		if err != nil {
+			return details, fmt.Errorf("failed to get metadata for %s: %w", p.Path, err)
+		}
+		details.CreatedTime = &meta.CreatedTime
+		details.UpdatedTime = &meta.UpdatedTime
+		details.CurrentVersion = meta.CurrentVersion
+		details.Versions = len(meta.Versions)
+		return details, nil
+	})
+
+	fetched := make([]pathDetails, 0, len(result))
+	errs := make([]error, 0)
+	for _, r := range result {
+		if r.err != nil {
+			errs = append(errs, r.err)
+			continue
+		}
+		fetched = append(fetched, r.value)
+	}
+	return fetched, errs
+}
+
+// printDetails writes details as a "text" table, a "json" array or "ndjson".
+func printDetails(out io.Writer, format string, details []pathDetails, withMount bool) error {
+	switch format {
+	case "json":
+		return json.NewEncoder(out).Encode(details)
+	case "ndjson":
+		encoder := json.NewEncoder(out)
+		for _, d := range details {
+			if err := encoder.Encode(d); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	formatTime := func(t *time.Time) string {
+		if t == nil {
+			return "-"
+		}
+		return t.Format(time.RFC3339)
+	}
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PATH\tCREATED\tUPDATED\tCURRENT\tVERSIONS")
+	for _, d := range details {
+		path := d.Path
+		if withMount {
+			path = d.Mount + "/" + path
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%d\n", path, formatTime(d.CreatedTime), formatTime(d.UpdatedTime), d.CurrentVersion, d.Versions)
+	}
+	return w.Flush()
+}
+
+// modifiedFilter selects secrets by the updated_time of their metadata.
+type modifiedFilter struct {
+	since  time.Time
+	before time.Time
+}
+
+// newModifiedFilter parses --modified-since and --modified-before, it returns
+// nil if neither is set.
+func newModifiedFilter(since, before string) (*modifiedFilter, error) {
+	if since == "" && before == "" {
+		return nil, nil
+	}
+	var filter modifiedFilter
+	var err error
+	if since != "" {
+		filter.since, err = parseTimeOrDuration(since)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --modified-since: %w", err)
+		}
+	}
+	if before != "" {
+		filter.before, err = parseTimeOrDuration(before)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --modified-before: %w", err)
+		}
+	}
+	return &filter, nil
+}
+
+// parseTimeOrDuration parses either an RFC 3339 timestamp or a duration,
+// which is taken to mean that long ago.
+func parseTimeOrDuration(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	duration, err := parseDuration(value)
+	if err != nil {
+		return time.Time{}, errors.New("expected an RFC 3339 timestamp or a duration like 168h or 7d")
+	}
+	return time.Now().Add(-duration), nil
+}
+
+// parseDuration is time.ParseDuration with support for whole days, e.g. "180d".
+func parseDuration(value string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		count, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", value)
+		}
+		return time.Duration(count) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(value)
+}
+
+// matches reports whether the secret was updated in the selected time range.
+// Directories have no metadata and never match.
+func (f *modifiedFilter) matches(details pathDetails) bool {
+	if details.UpdatedTime == nil {
+		return false
+	}
+	if !f.since.IsZero() && !details.UpdatedTime.After(f.since) {
+		return false
+	}
+	return f.before.IsZero() || details.UpdatedTime.Before(f.before)
+}
+
+// filter returns the details of the secrets updated in the selected time
+// range, a nil filter selects everything.
+func (f *modifiedFilter) filter(details []pathDetails) []pathDetails {
+	if f == nil {
+		return details
+	}
+	matching := make([]pathDetails, 0, len(details))
+	for _, d := range details {
+		if f.matches(d) {
+			matching = append(matching, d)
+		}
+	}
+	return matching
+}
+
+// fetchMountDetails gets the metadata of the paths found in mount.
+func fetchMountDetails(ctx context.Context, client *api.Client, mount string, paths []string) ([]pathDetails, []error) {
+	listed := make([]listedPath, len(paths))
+	for idx, path := range paths {
+		listed[idx] = listedPath{Mount: mount, Path: relativePath(path)}
+	}
+	return fetchDetails(ctx, client, listed)
+}
+
+// sortByUpdatedTime sorts details by their updated_time, directories without
+// metadata come first. Paths updated at the same time stay in their order.
+func sortByUpdatedTime(details []pathDetails) {
+	sort.SliceStable(details, func(i, j int) bool {
+		a, b := details[i].UpdatedTime, details[j].UpdatedTime
+		if a == nil || b == nil {
+			return a == nil && b != nil
+		}
+		return a.Before(*b)
+	})
+}
@@ -0,0 +1,227 @@
+/******************************************************************************
+*
+*  Copyright 2024 SAP SE
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"sync"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/urfave/cli/v2"
+)
+
+// changedValue is a custom metadata value that differs between two secrets.
+type changedValue struct {
+	Old any `json:"old"`
+	New any `json:"new"`
+}
+
+// metadataDiff lists the keys that differ between two custom metadata maps.
+type metadataDiff struct {
+	Added   map[string]any          `json:"added,omitempty"`
+	Removed map[string]any          `json:"removed,omitempty"`
+	Changed map[string]changedValue `json:"changed,omitempty"`
+}
+
+func diffMetadata(before, after map[string]any) metadataDiff {
+	diff := metadataDiff{
+		Added:   make(map[string]any),
+		Removed: make(map[string]any),
+		Changed: make(map[string]changedValue),
+	}
+	for key, oldValue := range before {
+		newValue, ok := after[key]
+		switch {
+		case !ok:
+			diff.Removed[key] = oldValue
+		case !reflect.DeepEqual(oldValue, newValue):
+			diff.Changed[key] = changedValue{Old: oldValue, New: newValue}
+		}
+	}
+	for key, newValue := range after {
+		if _, ok := before[key]; !ok {
+			diff.Added[key] = newValue
+		}
+	}
+	return diff
+}
+
+func (d metadataDiff) isEmpty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// write prints the diff with one key per line, sorted by key.
+func (d metadataDiff) write(w io.Writer) {
+	lines := make(map[string]string)
+	for key, value := range d.Added {
+		lines[key] = fmt.Sprintf("+ %s: %v", key, value)
+	}
+	for key, value := range d.Removed {
+		lines[key] = fmt.Sprintf("- %s: %v", key, value)
+	}
+	for key, value := range d.Changed {
+		lines[key] = fmt.Sprintf("~ %s: %v -> %v", key, value.Old, value.New)
+	}
+	keys := make([]string, 0, len(lines))
+	for key := range lines {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintln(w, lines[key])
+	}
+}
+
+func diffmetadata(ctx *cli.Context) error {
+	if ctx.NArg() != 2 {
+		return errors.New("expected exactly two path arguments")
+	}
+	client, err := createClient(ctx)
+	if err != nil {
+		return err
+	}
+	mountB := ctx.String("mount-b")
+	if mountB == "" {
+		mountB = ctx.String("mount")
+	}
+	mounts := []string{ctx.String("mount"), mountB}
+	result := make([]Result[map[string]any], 2)
+	var wg sync.WaitGroup
+	for idx, path := range pathArgs(ctx) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			meta, err := client.KVv2(mounts[idx]).GetMetadata(ctx.Context, path)
+			if err != nil {
+				result[idx] = Result[map[string]any]{err: fmt.Errorf("failed to get metadata for %s: %w", path, err)}
+				return
+			}
+			result[idx] = Result[map[string]any]{value: meta.CustomMetadata}
+		}()
+	}
+	wg.Wait()
+	for _, r := range result {
+		if r.err != nil {
+			return r.err
+		}
+	}
+
+	diff := diffMetadata(result[0].value, result[1].value)
+	diff.write(os.Stdout)
+	if ctx.Bool("exit-code") && !diff.isEmpty() {
+		return errDifferencesFound
+	}
+	return nil
+}
+
+// redacted returns a copy of the diff with all values hidden.
+func (d metadataDiff) redacted() metadataDiff {
+	const hidden = "<redacted>"
+	result := metadataDiff{
+		Added:   make(map[string]any, len(d.Added)),
+		Removed: make(map[string]any, len(d.Removed)),
+		Changed: make(map[string]changedValue, len(d.Changed)),
+	}
+	for key := range d.Added {
+		result.Added[key] = hidden
+	}
+	for key := range d.Removed {
+		result.Removed[key] = hidden
+	}
+	for key := range d.Changed {
+		result.Changed[key] = changedValue{Old: hidden, New: hidden}
+	}
+	return result
+}
+
+// diffSide is one of the secrets compared by kv diff.
+type diffSide struct {
+	mount   string
+	path    string
+	version int
+}
+
+func diffSecrets(ctx *cli.Context) error {
+	paths := pathArgs(ctx)
+	versionA, versionB := ctx.Int("version-a"), ctx.Int("version-b")
+	mountB := ctx.String("mount-b")
+	if mountB == "" {
+		mountB = ctx.String("mount")
+	}
+	var sides []diffSide
+	switch len(paths) {
+	case 1:
+		if versionA <= 0 || versionB <= 0 {
+			return errors.New("comparing versions of a single path requires --version-a and --version-b")
+		}
+		sides = []diffSide{{ctx.String("mount"), paths[0], versionA}, {ctx.String("mount"), paths[0], versionB}}
+	case 2:
+		sides = []diffSide{{ctx.String("mount"), paths[0], versionA}, {mountB, paths[1], versionB}}
+	default:
+		return errors.New("expected one path to compare versions of or two paths")
+	}
+	client, err := createClient(ctx)
+	if err != nil {
+		return err
+	}
+	result := mapConcurrently(ctx.Context, sides, func(side diffSide) (*api.KVSecret, error) {
+		kv := client.KVv2(side.mount)
+		var secret *api.KVSecret
+		var err error
+		if side.version > 0 {
+			secret, err = kv.GetVersion(ctx.Context, side.path, side.version)
+		} else {
+			secret, err = kv.Get(ctx.Context, side.path)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read secret %s: %w", side.path, err)
+		}
+		return secret, nil
+	})
+	for _, r := range result {
+		if r.err != nil {
+			return r.err
+		}
+	}
+
+	dataDiff := diffMetadata(result[0].value.Data, result[1].value.Data)
+	metaDiff := diffMetadata(result[0].value.CustomMetadata, result[1].value.CustomMetadata)
+	if !dataDiff.isEmpty() {
+		fmt.Println("data:")
+		if ctx.Bool("show-values") {
+			dataDiff.write(os.Stdout)
+		} else {
+			dataDiff.redacted().write(os.Stdout)
+		}
+	}
+	// custom metadata is not versioned, so it only differs between two paths
+	if !metaDiff.isEmpty() {
+		fmt.Println("custom_metadata:")
+		metaDiff.write(os.Stdout)
+	}
+	if ctx.Bool("exit-code") && (!dataDiff.isEmpty() || !metaDiff.isEmpty()) {
+		return errDifferencesFound
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+/******************************************************************************
+*
+*  Copyright 2024 SAP SE
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/urfave/cli/v2"
+)
+
+// dupeGroup is a set of secrets, or keys of secrets with --per-key, sharing
+// identical data as printed by kv dupes. The hash itself is not printed since
+// it would allow guessing weak secrets offline.
+type dupeGroup struct {
+	Paths []string `json:"paths" yaml:"paths"`
+}
+
+func dupes(ctx *cli.Context) (err error) {
+	format := ctx.String("format")
+	if err := checkFormat(format, "text", "json", "yaml"); err != nil {
+		return err
+	}
+	client, err := createClient(ctx)
+	if err != nil {
+		return err
+	}
+	out, err := openOutput(ctx)
+	if err != nil {
+		return err
+	}
+	defer closeOutput(out, &err)
+	lister := newLister(ctx, client)
+	paths, err := lister.listSecretDirRecurse(ctx.Context, "/")
+	if err != nil {
+		return err
+	}
+	sort.Strings(paths)
+
+	perKey := ctx.Bool("per-key")
+	// the hashes of each secret keyed by the path, or path#key with --per-key
+	result := mapConcurrently(ctx.Context, paths, func(path string) (map[string][sha256.Size]byte, error) {
+		path = relativePath(path)
+		var secret *api.KVSecret
+		var err error
+		if lister.kvVersion == 1 {
+			secret, err = client.KVv1(lister.mount).Get(ctx.Context, path)
+		} else {
+			secret, err = client.KVv2(lister.mount).Get(ctx.Context, path)
+		}
+		// deleted secrets have no data to compare
+		if errors.Is(err, api.ErrSecretNotFound) || (err == nil && secret.Data == nil) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read secret %s: %w", path, err)
+		}
+		hashes := make(map[string][sha256.Size]byte)
+		if !perKey {
+			// map keys are marshaled in sorted order, which makes this canonical
+			buf, err := json.Marshal(secret.Data)
+			if err != nil {
+				return nil, fmt.Errorf("failed to encode secret %s: %w", path, err)
+			}
+			hashes[path] = sha256.Sum256(buf)
+			return hashes, nil
+		}
+		for key, value := range secret.Data {
+			buf, err := json.Marshal(value)
+			if err != nil {
+				return nil, fmt.Errorf("failed to encode %s of secret %s: %w", key, path, err)
+			}
+			hashes[path+"#"+key] = sha256.Sum256(buf)
+		}
+		return hashes, nil
+	})
+
+	byHash := make(map[[sha256.Size]byte][]string)
+	errs := make([]error, 0)
+	for _, r := range result {
+		if r.err != nil {
+			errs = append(errs, r.err)
+			continue
+		}
+		for location, hash := range r.value {
+			byHash[hash] = append(byHash[hash], location)
+		}
+	}
+	groups := make([]dupeGroup, 0)
+	for _, locations := range byHash {
+		if len(locations) > 1 {
+			sort.Strings(locations)
+			groups = append(groups, dupeGroup{Paths: locations})
+		}
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Paths[0] < groups[j].Paths[0] })
+
+	if format != "text" {
+		err = encodeOutput(out, format, groups)
+	} else {
+		for idx, group := range groups {
+			if idx > 0 {
+				fmt.Fprintln(out)
+			}
+			for _, location := range group.Paths {
+				fmt.Fprintln(out, location)
+			}
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		return joinErrors(errs, len(paths)-len(errs))
+	}
+	if len(groups) == 0 {
+		return errNoResults
+	}
+	return nil
+}
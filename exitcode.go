@@ -0,0 +1,81 @@
+/******************************************************************************
+*
+*  Copyright 2024 SAP SE
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package main
+
+import (
+	"errors"
+)
+
+// Exit codes of mutavault, see the README.
+const (
+	exitSuccess        = 0
+	exitFailure        = 1
+	exitPartialFailure = 2
+	exitNoResults      = 3
+	exitDifferences    = 4
+	exitInterrupted    = 130
+)
+
+// errNoResults is returned by commands that completed without finding anything.
+var errNoResults = errors.New("no results")
+
+// errDifferencesFound is returned with --exit-code by commands that found
+// differences or violations. Like errNoResults, it is not printed.
+var errDifferencesFound = errors.New("differences found")
+
+// partialFailureError wraps the errors of a command that succeeded for some
+// paths and failed for others.
+type partialFailureError struct {
+	err error
+}
+
+func (e partialFailureError) Error() string {
+	return e.err.Error()
+}
+
+func (e partialFailureError) Unwrap() error {
+	return e.err
+}
+
+// joinErrors joins errs and marks them as a partial failure if some
+// operations succeeded.
+func joinErrors(errs []error, succeeded int) error {
+	err := errors.Join(errs...)
+	if err == nil || succeeded == 0 {
+		return err
+	}
+	return partialFailureError{err}
+}
+
+// exitCode maps the error returned by a command to the exit code of mutavault.
+func exitCode(err error) int {
+	var partial partialFailureError
+	switch {
+	case err == nil:
+		return exitSuccess
+	case errors.Is(err, errNoResults):
+		return exitNoResults
+	case errors.Is(err, errDifferencesFound):
+		return exitDifferences
+	case errors.As(err, &partial):
+		return exitPartialFailure
+	default:
+		return exitFailure
+	}
+}
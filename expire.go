@@ -0,0 +1,170 @@
+/******************************************************************************
+*
+*  Copyright 2024 SAP SE
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/urfave/cli/v2"
+)
+
+// expiredSecret is an entry of the report printed by kv expire.
+type expiredSecret struct {
+	Path      string    `json:"path"            yaml:"path"`
+	ExpiresAt time.Time `json:"expires_at"      yaml:"expires_at"`
+	// "warned", "deleted", "destroyed" or "failed"
+	Action string `json:"action"          yaml:"action"`
+	Error  string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// parseExpiry parses an expiry stamp, which is either an RFC 3339 timestamp
+// or a date.
+func parseExpiry(value any) (time.Time, error) {
+	str, ok := value.(string)
+	if !ok {
+		return time.Time{}, fmt.Errorf("expected a timestamp instead of %s", describeValue(value))
+	}
+	if t, err := time.Parse(time.RFC3339, str); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.DateOnly, str)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("expected an RFC 3339 timestamp or a date instead of %q", str)
+	}
+	return t, nil
+}
+
+// expiry returns the expiry stamp under key of a secret and whether it has
+// expired at now. Secrets without a readable version are not enforced since
+// there is nothing left to delete.
+func expiry(meta *api.KVMetadata, key string, now time.Time) (expiresAt time.Time, expired bool, err error) {
+	value, ok := meta.CustomMetadata[key]
+	if !ok {
+		return time.Time{}, false, nil
+	}
+	expiresAt, err = parseExpiry(value)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	return expiresAt, !expiresAt.After(now) && !isOrphan(meta), nil
+}
+
+func expire(ctx *cli.Context) (err error) {
+	format := ctx.String("format")
+	if err := checkFormat(format, "table", "json", "yaml"); err != nil {
+		return err
+	}
+	action := ctx.String("action")
+	verbs := map[string]string{"warn": "warned", "delete": "deleted", "destroy": "destroyed"}
+	if _, ok := verbs[action]; !ok {
+		return fmt.Errorf("unsupported action %q, expected warn, delete or destroy", action)
+	}
+	client, err := createClient(ctx)
+	if err != nil {
+		return err
+	}
+	out, err := openOutput(ctx)
+	if err != nil {
+		return err
+	}
+	defer closeOutput(out, &err)
+	kv := client.KVv2(ctx.String("mount"))
+	lister := newLister(ctx, client)
+	paths, err := lister.listSecretDirRecurse(ctx.Context, "/")
+	if err != nil {
+		return err
+	}
+	sort.Strings(paths)
+
+	key := ctx.String("key")
+	now := time.Now()
+	// secrets which have not expired have an empty path
+	result := mapConcurrently(ctx.Context, paths, func(path string) (expiredSecret, error) {
+		path = relativePath(path)
+		meta, err := kv.GetMetadata(ctx.Context, path)
+		if err != nil {
+			return expiredSecret{}, fmt.Errorf("failed to get metadata for %s: %w", path, err)
+		}
+		expiresAt, expired, err := expiry(meta, key, now)
+		if err != nil {
+			return expiredSecret{}, fmt.Errorf("invalid %s of %s: %w", key, path, err)
+		}
+		if !expired {
+			return expiredSecret{}, nil
+		}
+		return expiredSecret{Path: path, ExpiresAt: expiresAt, Action: verbs["warn"]}, nil
+	})
+
+	expired := make([]expiredSecret, 0)
+	errs := make([]error, 0)
+	for _, r := range result {
+		switch {
+		case r.err != nil:
+			errs = append(errs, r.err)
+		case r.value.Path != "":
+			expired = append(expired, r.value)
+		}
+	}
+
+	if action != "warn" && len(expired) > 0 {
+		if err := confirm(ctx, action, len(expired)); err != nil {
+			return err
+		}
+		applied := mapConcurrently(ctx.Context, expired, func(secret expiredSecret) (expiredSecret, error) {
+			var err error
+			if action == "delete" {
+				err = deleteLatestVersion(kv, ctx.Context, secret.Path)
+			} else {
+				err = destroyAllVersions(kv, ctx.Context, secret.Path)
+			}
+			if err != nil {
+				secret.Action = "failed"
+				secret.Error = err.Error()
+				return secret, fmt.Errorf("failed to %s %s: %w", action, secret.Path, err)
+			}
+			secret.Action = verbs[action]
+			return secret, nil
+		})
+		for idx, r := range applied {
+			expired[idx] = r.value
+			if r.err != nil {
+				errs = append(errs, r.err)
+			}
+		}
+	}
+
+	if format != "table" {
+		err = encodeOutput(out, format, expired)
+	} else {
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "PATH\tEXPIRES AT\tACTION")
+		for _, s := range expired {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", s.Path, s.ExpiresAt.Format(time.RFC3339), s.Action)
+		}
+		err = w.Flush()
+	}
+	if err != nil {
+		return err
+	}
+	return joinErrors(errs, len(paths)-len(errs))
+}
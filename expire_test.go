@@ -0,0 +1,57 @@
+/******************************************************************************
+*
+*  Copyright 2024 SAP SE
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+)
+
+func TestExpiry(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	live := map[string]api.KVVersionMetadata{"1": {Version: 1}}
+	// delete_version_after puts a future deletion time on live versions
+	scheduled := map[string]api.KVVersionMetadata{"1": {Version: 1, DeletionTime: time.Now().Add(time.Hour)}}
+	deleted := map[string]api.KVVersionMetadata{"1": {Version: 1, DeletionTime: time.Now().Add(-time.Hour)}}
+	cases := []struct {
+		name     string
+		meta     api.KVMetadata
+		expected bool
+		invalid  bool
+	}{
+		{"no stamp", api.KVMetadata{Versions: live}, false, false},
+		{"not yet expired", api.KVMetadata{Versions: live, CustomMetadata: map[string]any{"expires-at": "2024-07-01"}}, false, false},
+		{"expired date", api.KVMetadata{Versions: live, CustomMetadata: map[string]any{"expires-at": "2024-05-01"}}, true, false},
+		{"expired timestamp", api.KVMetadata{Versions: live, CustomMetadata: map[string]any{"expires-at": "2024-06-01T11:00:00Z"}}, true, false},
+		{"deletion scheduled", api.KVMetadata{Versions: scheduled, CustomMetadata: map[string]any{"expires-at": "2024-05-01"}}, true, false},
+		{"already deleted", api.KVMetadata{Versions: deleted, CustomMetadata: map[string]any{"expires-at": "2024-05-01"}}, false, false},
+		{"invalid stamp", api.KVMetadata{Versions: live, CustomMetadata: map[string]any{"expires-at": "soon"}}, false, true},
+	}
+	for _, c := range cases {
+		_, expired, err := expiry(&c.meta, "expires-at", now)
+		if (err != nil) != c.invalid {
+			t.Errorf("%s: unexpected error %v", c.name, err)
+		}
+		if expired != c.expected {
+			t.Errorf("%s: expired = %t, expected %t", c.name, expired, c.expected)
+		}
+	}
+}
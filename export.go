@@ -0,0 +1,171 @@
+/******************************************************************************
+*
+*  Copyright 2024 SAP SE
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/urfave/cli/v2"
+)
+
+// exportRecord is a single line of the newline-delimited JSON produced by kv export.
+type exportRecord struct {
+	Path               string         `json:"path"`
+	Version            int            `json:"version"`
+	Data               map[string]any `json:"data"`
+	CustomMetadata     map[string]any `json:"custom_metadata"`
+	MaxVersions        int            `json:"max_versions,omitempty"`
+	CASRequired        bool           `json:"cas_required,omitempty"`
+	DeleteVersionAfter string         `json:"delete_version_after,omitempty"`
+	// only set with --all-versions, oldest first
+	Versions []exportVersion `json:"versions,omitempty"`
+}
+
+// exportVersion is a single version of an exported secret. State is set
+// instead of Data if the version has been deleted or destroyed.
+type exportVersion struct {
+	Version int            `json:"version"`
+	Data    map[string]any `json:"data,omitempty"`
+	State   string         `json:"state,omitempty"`
+}
+
+func export(ctx *cli.Context) (err error) {
+	client, err := createClient(ctx)
+	if err != nil {
+		return err
+	}
+	out, err := openOutput(ctx)
+	if err != nil {
+		return err
+	}
+	defer closeOutput(out, &err)
+	buffered := bufio.NewWriter(out)
+	encoder := json.NewEncoder(buffered)
+
+	mount := ctx.String("mount")
+	allVersions := ctx.Bool("all-versions")
+	lister := newLister(ctx, client)
+	paths, err := lister.listSecretDirRecurse(ctx.Context, "/")
+	if err != nil {
+		return err
+	}
+
+	errs := make([]error, 0)
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	for _, path := range paths {
+		path = relativePath(path)
+		// acquiring before spawning bounds the number of records held in memory
+		if err := lister.sema.Acquire(ctx.Context, 1); err != nil {
+			mutex.Lock()
+			errs = append(errs, err)
+			mutex.Unlock()
+			break
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			record, err := exportSecret(ctx, client, mount, path, allVersions)
+			lister.sema.Release(1)
+			mutex.Lock()
+			defer mutex.Unlock()
+			if errors.Is(err, api.ErrSecretNotFound) {
+				slog.Warn("skipping secret, the latest version is deleted", "path", path)
+				return
+			}
+			if err == nil {
+				err = encoder.Encode(record)
+			}
+			if err != nil {
+				errs = append(errs, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if err := buffered.Flush(); err != nil {
+		return err
+	}
+	return joinErrors(errs, len(paths)-len(errs))
+}
+
+func exportSecret(ctx *cli.Context, client *api.Client, mount, path string, allVersions bool) (exportRecord, error) {
+	kv := client.KVv2(mount)
+	meta, err := kv.GetMetadata(ctx.Context, path)
+	if err != nil {
+		return exportRecord{}, fmt.Errorf("failed to get metadata for %s: %w", path, err)
+	}
+	record := exportRecord{
+		Path:           path,
+		Version:        meta.CurrentVersion,
+		CustomMetadata: meta.CustomMetadata,
+		MaxVersions:    meta.MaxVersions,
+		CASRequired:    meta.CASRequired,
+	}
+	if meta.DeleteVersionAfter > 0 {
+		record.DeleteVersionAfter = meta.DeleteVersionAfter.String()
+	}
+	if !allVersions {
+		secret, err := kv.Get(ctx.Context, path)
+		// a deleted latest version is returned with its metadata but without data
+		if err == nil && secret.Data == nil {
+			err = api.ErrSecretNotFound
+		}
+		if err != nil {
+			return exportRecord{}, fmt.Errorf("failed to read secret %s: %w", path, err)
+		}
+		record.Version = secret.VersionMetadata.Version
+		record.Data = secret.Data
+		return record, nil
+	}
+
+	versions := make([]int, 0, len(meta.Versions))
+	for _, v := range meta.Versions {
+		versions = append(versions, v.Version)
+	}
+	sort.Ints(versions)
+	for _, version := range versions {
+		exported := exportVersion{Version: version}
+		secret, err := kv.GetVersion(ctx.Context, path, version)
+		if err != nil {
+			return exportRecord{}, fmt.Errorf("failed to read version %d of secret %s: %w", version, path, err)
+		}
+		// deleted and destroyed versions only come with their metadata
+		switch {
+		case secret.VersionMetadata != nil && secret.VersionMetadata.Destroyed:
+			exported.State = "destroyed"
+		case secret.Data == nil:
+			exported.State = "deleted"
+		default:
+			exported.Data = secret.Data
+		}
+		// like without --all-versions, a deleted current version has no data
+		if version == meta.CurrentVersion && exported.State == "" {
+			record.Data = exported.Data
+		}
+		record.Versions = append(record.Versions, exported)
+	}
+	return record, nil
+}
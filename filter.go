@@ -0,0 +1,195 @@
+/******************************************************************************
+*
+*  Copyright 2024 SAP SE
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/urfave/cli/v2"
+)
+
+// pathFilter selects the secrets emitted by a traversal. Paths are matched in
+// their slash-stripped form, e.g. "a/b" for the secret b in directory a. In
+// globs, a "**" segment matches any number of directories.
+type pathFilter struct {
+	glob  string
+	regex *regexp.Regexp
+}
+
+// newPathFilter creates a filter from either a glob or a regular expression.
+func newPathFilter(glob, regex string) (*pathFilter, error) {
+	switch {
+	case glob != "" && regex != "":
+		return nil, errors.New("a glob and a regular expression are mutually exclusive")
+	case regex != "":
+		compiled, err := regexp.Compile(regex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression: %w", err)
+		}
+		return &pathFilter{regex: compiled}, nil
+	default:
+		if _, err := path.Match(glob, ""); err != nil {
+			return nil, fmt.Errorf("invalid glob %q: %w", glob, err)
+		}
+		return &pathFilter{glob: glob}, nil
+	}
+}
+
+// matches reports whether the secret at the given path is selected.
+func (f *pathFilter) matches(secretPath string) bool {
+	if f.regex != nil {
+		return f.regex.MatchString(secretPath)
+	}
+	return matchSegments(strings.Split(f.glob, "/"), strings.Split(secretPath, "/"))
+}
+
+// matchSegments matches a glob split at slashes against a path split at
+// slashes, "**" segments match zero or more path segments.
+func matchSegments(pattern, segments []string) bool {
+	if len(pattern) == 0 {
+		return len(segments) == 0
+	}
+	if pattern[0] == "**" {
+		return matchSegments(pattern[1:], segments) || (len(segments) > 0 && matchSegments(pattern, segments[1:]))
+	}
+	if len(segments) == 0 {
+		return false
+	}
+	ok, err := path.Match(pattern[0], segments[0])
+	return err == nil && ok && matchSegments(pattern[1:], segments[1:])
+}
+
+// mayMatchBelow reports whether any secret below the given directory can be
+// selected, which allows skipping the traversal of entire subtrees.
+func (f *pathFilter) mayMatchBelow(dir string) bool {
+	if f.regex != nil {
+		return true
+	}
+	// path.Match never lets a wildcard cross a slash, so every segment of the
+	// directory has to match the corresponding segment of the pattern until a
+	// "**" allows anything below
+	patternSegments := strings.Split(f.glob, "/")
+	dirSegments := strings.Split(strings.Trim(dir, "/"), "/")
+	for idx, segment := range dirSegments {
+		if idx >= len(patternSegments) {
+			return false
+		}
+		if patternSegments[idx] == "**" {
+			return true
+		}
+		if ok, err := path.Match(patternSegments[idx], segment); err != nil || !ok {
+			return false
+		}
+	}
+	return len(patternSegments) > len(dirSegments)
+}
+
+// isGlob reports whether a path given as argument is a glob to be expanded.
+func isGlob(arg string) bool {
+	return strings.ContainsAny(arg, "*?[")
+}
+
+// expandGlob returns the secrets matching the glob in sorted order. Only the
+// directories below the segments without wildcards are walked.
+func expandGlob(ctx *cli.Context, client *api.Client, glob string) ([]string, error) {
+	filter, err := newPathFilter(glob, "")
+	if err != nil {
+		return nil, err
+	}
+	start := "/"
+	segments := strings.Split(glob, "/")
+	for _, segment := range segments[:len(segments)-1] {
+		if isGlob(segment) {
+			break
+		}
+		start += segment + "/"
+	}
+	lister := newLister(ctx, client)
+	lister.filter = filter
+	// directories returned because of --max-depth are not secrets
+	lister.maxDepth = 0
+	paths, err := lister.listSecretDirRecurse(ctx.Context, start)
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		slog.Warn("no secrets match the glob", "glob", glob)
+	}
+	sort.Strings(paths)
+	for idx, p := range paths {
+		paths[idx] = relativePath(p)
+	}
+	return paths, nil
+}
+
+// newPathPatterns parses --include and --exclude patterns, which are globs
+// unless they are prefixed with "re:".
+func newPathPatterns(patterns []string) ([]*pathFilter, error) {
+	filters := make([]*pathFilter, 0, len(patterns))
+	for _, pattern := range patterns {
+		var filter *pathFilter
+		var err error
+		if regex, ok := strings.CutPrefix(pattern, "re:"); ok {
+			filter, err = newPathFilter("", regex)
+		} else {
+			filter, err = newPathFilter(pattern, "")
+		}
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, filter)
+	}
+	return filters, nil
+}
+
+// matchesAny reports whether any of the filters selects the given path.
+func matchesAny(filters []*pathFilter, secretPath string) bool {
+	for _, f := range filters {
+		if f.matches(secretPath) {
+			return true
+		}
+	}
+	return false
+}
+
+// readPathsExpanded returns the paths like readPaths with globs expanded to
+// the matching secrets.
+func readPathsExpanded(ctx *cli.Context, client *api.Client) ([]string, error) {
+	paths := make([]string, 0)
+	err := forEachPath(ctx, func(path string) error {
+		if !isGlob(path) {
+			paths = append(paths, path)
+			return nil
+		}
+		expanded, err := expandGlob(ctx, client, path)
+		paths = append(paths, expanded...)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return paths, nil
+}
@@ -0,0 +1,100 @@
+/******************************************************************************
+*
+*  Copyright 2024 SAP SE
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/urfave/cli/v2"
+)
+
+func findmetas(ctx *cli.Context) (err error) {
+	expr, err := parseMetaExpr(ctx.String("where"))
+	if err != nil {
+		return fmt.Errorf("invalid --where expression: %w", err)
+	}
+	full := ctx.Bool("full")
+	format := ctx.String("format")
+	if err := checkFormat(format, "json", "yaml"); err != nil {
+		return err
+	}
+	pathKey := ctx.String("path-key")
+	client, err := createClient(ctx)
+	if err != nil {
+		return err
+	}
+	out, err := openOutput(ctx)
+	if err != nil {
+		return err
+	}
+	defer closeOutput(out, &err)
+	kv := client.KVv2(ctx.String("mount"))
+	lister := newLister(ctx, client)
+	paths, err := lister.listSecretDirRecurse(ctx.Context, "/")
+	if err != nil {
+		return err
+	}
+	sort.Strings(paths)
+
+	// only the matching custom metadata is kept, nil means no match
+	result := mapConcurrently(ctx.Context, paths, func(path string) (map[string]any, error) {
+		path = relativePath(path)
+		meta, err := kv.GetMetadata(ctx.Context, path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get metadata for %s: %w", path, err)
+		}
+		customMeta := meta.CustomMetadata
+		if customMeta == nil {
+			customMeta = make(map[string]any)
+		}
+		if !expr.eval(customMeta) {
+			return nil, nil
+		}
+		if _, exists := customMeta[pathKey]; exists && full {
+			return nil, fmt.Errorf("custom metadata of %s already contains the key %q, choose another --path-key", path, pathKey)
+		}
+		customMeta[pathKey] = path
+		return customMeta, nil
+	})
+
+	matches := make([]map[string]any, 0)
+	errs := make([]error, 0)
+	for _, r := range result {
+		switch {
+		case r.err != nil:
+			errs = append(errs, r.err)
+		case r.value != nil:
+			matches = append(matches, r.value)
+		}
+	}
+	if len(matches) == 0 && len(errs) == 0 {
+		return errNoResults
+	}
+	if full {
+		if err := encodeOutput(out, format, matches); err != nil {
+			return err
+		}
+	} else {
+		for _, customMeta := range matches {
+			fmt.Fprintln(out, customMeta[pathKey])
+		}
+	}
+	return joinErrors(errs, len(paths)-len(errs))
+}
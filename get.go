@@ -0,0 +1,129 @@
+/******************************************************************************
+*
+*  Copyright 2024 SAP SE
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/urfave/cli/v2"
+	"golang.org/x/sync/semaphore"
+)
+
+// secretData is the representation of a secret as consumed and produced by
+// the data commands.
+type secretData struct {
+	Path string         `json:"path"`
+	Data map[string]any `json:"data"`
+	// only set by get for kvv2 engines, ignored by put
+	Version int `json:"version,omitempty"`
+}
+
+func get(ctx *cli.Context) (err error) {
+	client, err := createClient(ctx)
+	if err != nil {
+		return err
+	}
+	out, err := openOutput(ctx)
+	if err != nil {
+		return err
+	}
+	defer closeOutput(out, &err)
+	version := ctx.Int("version")
+	kvVersion := ctx.Int("kv-version")
+	if kvVersion == 1 && version > 0 {
+		return errors.New("kvv1 engines do not support versions")
+	}
+	paths, err := readPaths(ctx)
+	if err != nil {
+		return err
+	}
+	result := make([]Result[secretData], 0)
+	missing := make([]string, 0)
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	sema := semaphore.NewWeighted(concurrency)
+
+	for _, path := range paths {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := sema.Acquire(ctx.Context, 1); err != nil {
+				mutex.Lock()
+				defer mutex.Unlock()
+				result = append(result, Result[secretData]{err: err})
+				return
+			}
+			var secret *api.KVSecret
+			var err error
+			switch {
+			case kvVersion == 1:
+				secret, err = client.KVv1(ctx.String("mount")).Get(ctx.Context, path)
+			case version > 0:
+				secret, err = client.KVv2(ctx.String("mount")).GetVersion(ctx.Context, path, version)
+			default:
+				secret, err = client.KVv2(ctx.String("mount")).Get(ctx.Context, path)
+			}
+			sema.Release(1)
+			mutex.Lock()
+			defer mutex.Unlock()
+			// deleted versions are returned with their metadata but without data
+			if errors.Is(err, api.ErrSecretNotFound) || (err == nil && secret.Data == nil) {
+				missing = append(missing, path)
+				return
+			}
+			if err != nil {
+				result = append(result, Result[secretData]{err: fmt.Errorf("failed to read secret %s: %w", path, err)})
+				return
+			}
+			value := secretData{Path: path, Data: secret.Data}
+			if secret.VersionMetadata != nil {
+				value.Version = secret.VersionMetadata.Version
+			}
+			result = append(result, Result[secretData]{value: value})
+		}()
+	}
+
+	wg.Wait()
+	secrets := make([]secretData, 0)
+	for _, r := range result {
+		if r.err != nil {
+			return r.err
+		}
+		secrets = append(secrets, r.value)
+	}
+	encoder := json.NewEncoder(out)
+	if ctx.Bool("ndjson") {
+		for _, secret := range secrets {
+			if err := encoder.Encode(secret); err != nil {
+				return err
+			}
+		}
+	} else if err := encoder.Encode(secrets); err != nil {
+		return err
+	}
+	if len(missing) > 0 {
+		return joinErrors([]error{fmt.Errorf("secrets do not exist or are deleted: %s", strings.Join(missing, ", "))}, len(secrets))
+	}
+	return nil
+}
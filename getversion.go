@@ -0,0 +1,95 @@
+/******************************************************************************
+*
+*  Copyright 2024 SAP SE
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/urfave/cli/v2"
+)
+
+// versionedSecret is a secret pinned to a specific version as consumed and
+// produced by kv get-version. State is set instead of Data if the version
+// has been deleted or destroyed.
+type versionedSecret struct {
+	Path    string         `json:"path"`
+	Version int            `json:"version"`
+	Data    map[string]any `json:"data,omitempty"`
+	State   string         `json:"state,omitempty"`
+}
+
+func getVersion(ctx *cli.Context) (err error) {
+	client, err := createClient(ctx)
+	if err != nil {
+		return err
+	}
+	requests := make([]versionedSecret, 0)
+	if err = json.NewDecoder(os.Stdin).Decode(&requests); err != nil {
+		return fmt.Errorf("failed to decode stdin, expected an array of path and version objects: %w", err)
+	}
+	for idx, request := range requests {
+		requests[idx].Path = cleanPath(request.Path)
+		if requests[idx].Path == "" || request.Version < 1 {
+			return fmt.Errorf("object %d needs a path and a positive version", idx)
+		}
+	}
+	out, err := openOutput(ctx)
+	if err != nil {
+		return err
+	}
+	defer closeOutput(out, &err)
+
+	kv := client.KVv2(ctx.String("mount"))
+	result := mapConcurrently(ctx.Context, requests, func(request versionedSecret) (versionedSecret, error) {
+		secret, err := kv.GetVersion(ctx.Context, request.Path, request.Version)
+		if err != nil {
+			return request, fmt.Errorf("failed to read version %d of secret %s: %w", request.Version, request.Path, err)
+		}
+		// deleted and destroyed versions only come with their metadata
+		switch {
+		case secret.VersionMetadata != nil && secret.VersionMetadata.Destroyed:
+			request.State = "destroyed"
+		case secret.Data == nil:
+			request.State = "deleted"
+		default:
+			request.Data = secret.Data
+		}
+		if request.State != "" {
+			slog.Warn("version has no data", "path", request.Path, "version", request.Version, "state", request.State)
+		}
+		return request, nil
+	})
+
+	secrets := make([]versionedSecret, 0, len(result))
+	errs := make([]error, 0)
+	for _, r := range result {
+		if r.err != nil {
+			errs = append(errs, r.err)
+			continue
+		}
+		secrets = append(secrets, r.value)
+	}
+	if err := json.NewEncoder(out).Encode(secrets); err != nil {
+		return err
+	}
+	return joinErrors(errs, len(secrets))
+}
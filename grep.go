@@ -0,0 +1,130 @@
+/******************************************************************************
+*
+*  Copyright 2024 SAP SE
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/urfave/cli/v2"
+)
+
+// grepMatch is a key of a secret whose name or value matched kv grep.
+type grepMatch struct {
+	path  string
+	key   string
+	value string
+}
+
+// valueString returns the value of a secret key as matched and printed by
+// kv grep, values which are not strings are JSON-encoded.
+func valueString(value any) string {
+	if str, ok := value.(string); ok {
+		return str
+	}
+	buf, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(buf)
+}
+
+func grep(ctx *cli.Context) (err error) {
+	if ctx.NArg() != 1 {
+		return errors.New("expected exactly one regular expression")
+	}
+	pattern, err := regexp.Compile(ctx.Args().First())
+	if err != nil {
+		return fmt.Errorf("invalid regular expression: %w", err)
+	}
+	matchKeys, matchValues := !ctx.Bool("values-only"), !ctx.Bool("keys-only")
+	if !matchKeys && !matchValues {
+		return errors.New("--keys-only and --values-only are mutually exclusive")
+	}
+	client, err := createClient(ctx)
+	if err != nil {
+		return err
+	}
+	out, err := openOutput(ctx)
+	if err != nil {
+		return err
+	}
+	defer closeOutput(out, &err)
+	lister := newLister(ctx, client)
+	paths, err := lister.listSecretDirRecurse(ctx.Context, normalizeDir(ctx.String("prefix")))
+	if err != nil {
+		return err
+	}
+	sort.Strings(paths)
+
+	result := mapConcurrently(ctx.Context, paths, func(path string) ([]grepMatch, error) {
+		path = relativePath(path)
+		var secret *api.KVSecret
+		var err error
+		if lister.kvVersion == 1 {
+			secret, err = client.KVv1(lister.mount).Get(ctx.Context, path)
+		} else {
+			secret, err = client.KVv2(lister.mount).Get(ctx.Context, path)
+		}
+		if errors.Is(err, api.ErrSecretNotFound) || (err == nil && secret.Data == nil) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read secret %s: %w", path, err)
+		}
+		matches := make([]grepMatch, 0)
+		for key, value := range secret.Data {
+			str := valueString(value)
+			if (matchKeys && pattern.MatchString(key)) || (matchValues && pattern.MatchString(str)) {
+				matches = append(matches, grepMatch{path: path, key: key, value: str})
+			}
+		}
+		sort.Slice(matches, func(i, j int) bool { return matches[i].key < matches[j].key })
+		return matches, nil
+	})
+
+	showValues := ctx.Bool("show-values")
+	found := 0
+	errs := make([]error, 0)
+	for _, r := range result {
+		if r.err != nil {
+			errs = append(errs, r.err)
+			continue
+		}
+		for _, match := range r.value {
+			found++
+			if showValues {
+				fmt.Fprintf(out, "%s\t%s=%s\n", match.path, match.key, match.value)
+			} else {
+				fmt.Fprintf(out, "%s\t%s\n", match.path, match.key)
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return joinErrors(errs, len(paths)-len(errs))
+	}
+	if found == 0 {
+		return errNoResults
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+/******************************************************************************
+*
+*  Copyright 2024 SAP SE
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/urfave/cli/v2"
+	"golang.org/x/sync/semaphore"
+)
+
+func importSecrets(ctx *cli.Context) error {
+	client, err := createClient(ctx)
+	if err != nil {
+		return err
+	}
+	var in io.Reader = os.Stdin
+	if ctx.IsSet("input") {
+		file, err := os.Open(ctx.String("input"))
+		if err != nil {
+			return fmt.Errorf("failed to open input file: %w", err)
+		}
+		defer file.Close()
+		in = file
+	}
+	decoder := json.NewDecoder(bufio.NewReader(in))
+
+	mount := ctx.String("mount")
+	mode := importAppend
+	switch {
+	case ctx.Bool("skip-existing") && ctx.Bool("overwrite"):
+		return errors.New("--skip-existing and --overwrite are mutually exclusive")
+	case ctx.Bool("skip-existing"):
+		mode = importSkipExisting
+	case ctx.Bool("overwrite"):
+		mode = importOverwrite
+	}
+	var created, skipped int
+	errs := make([]error, 0)
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	sema := semaphore.NewWeighted(concurrency)
+	for {
+		var record exportRecord
+		err := decoder.Decode(&record)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err == nil {
+			record.Path = cleanPath(record.Path)
+			err = sema.Acquire(ctx.Context, 1)
+		}
+		if err != nil {
+			mutex.Lock()
+			errs = append(errs, err)
+			mutex.Unlock()
+			break
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			written, err := importSecret(ctx, client, mount, record, mode)
+			sema.Release(1)
+			mutex.Lock()
+			defer mutex.Unlock()
+			switch {
+			case err != nil:
+				errs = append(errs, err)
+			case written:
+				created++
+			default:
+				skipped++
+			}
+		}()
+	}
+	wg.Wait()
+	fmt.Printf("created: %d, skipped: %d, failed: %d\n", created, skipped, len(errs))
+	return joinErrors(errs, created+skipped)
+}
+
+// importMode selects how import treats secrets that already exist.
+type importMode int
+
+const (
+	// existing secrets get the imported data as new versions
+	importAppend importMode = iota
+	// existing secrets are left untouched
+	importSkipExisting
+	// existing secrets are removed including their history before importing
+	importOverwrite
+)
+
+// importSecret writes a single exported record and reports whether it was written.
+func importSecret(ctx *cli.Context, client *api.Client, mount string, record exportRecord, mode importMode) (bool, error) {
+	// exports of secrets whose latest version was deleted have no data
+	if len(record.Versions) == 0 && record.Data == nil {
+		slog.Warn("skipping record without data", "path", record.Path)
+		return false, nil
+	}
+	kv := client.KVv2(mount)
+	switch mode {
+	case importSkipExisting:
+		exists, err := secretExists(ctx.Context, client, mount, record.Path)
+		if err != nil {
+			return false, err
+		}
+		if exists {
+			return false, nil
+		}
+	case importOverwrite:
+		if err := kv.DeleteMetadata(ctx.Context, record.Path); err != nil {
+			return false, fmt.Errorf("failed to delete existing secret %s: %w", record.Path, err)
+		}
+	}
+
+	versions := record.Versions
+	if len(versions) == 0 {
+		versions = []exportVersion{{Version: record.Version, Data: record.Data}}
+	}
+	for _, version := range versions {
+		// versions without data cannot be restored and are kept as placeholders
+		if version.State == "" && version.Data == nil {
+			slog.Warn("importing version without data as deleted", "path", record.Path, "version", version.Version)
+			version.State = "deleted"
+		}
+		if version.State == "" {
+			if _, err := kv.Put(ctx.Context, record.Path, version.Data); err != nil {
+				return false, fmt.Errorf("failed to write version %d of secret %s: %w", version.Version, record.Path, err)
+			}
+			continue
+		}
+		// the data of deleted and destroyed versions is gone, a destroyed
+		// placeholder keeps the following versions at their original numbers
+		written, err := kv.Put(ctx.Context, record.Path, map[string]any{})
+		if err == nil {
+			err = kv.Destroy(ctx.Context, record.Path, []int{written.VersionMetadata.Version})
+		}
+		if err != nil {
+			return false, fmt.Errorf("failed to write placeholder for %s version %d of secret %s: %w", version.State, version.Version, record.Path, err)
+		}
+	}
+
+	input := api.KVMetadataPutInput{
+		CASRequired:    record.CASRequired,
+		CustomMetadata: record.CustomMetadata,
+		MaxVersions:    record.MaxVersions,
+	}
+	if record.DeleteVersionAfter != "" {
+		deleteVersionAfter, err := time.ParseDuration(record.DeleteVersionAfter)
+		if err != nil {
+			return false, fmt.Errorf("invalid delete_version_after of secret %s: %w", record.Path, err)
+		}
+		input.DeleteVersionAfter = deleteVersionAfter
+	}
+	if err := kv.PutMetadata(ctx.Context, record.Path, input); err != nil {
+		return false, fmt.Errorf("failed to update metadata for %s: %w", record.Path, err)
+	}
+	return true, nil
+}
@@ -0,0 +1,100 @@
+/******************************************************************************
+*
+*  Copyright 2024 SAP SE
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/urfave/cli/v2"
+)
+
+func destroy(ctx *cli.Context) error {
+	return changeVersions(ctx, "destroyed", "permanently destroy versions of", false, (*api.KVv2).Destroy)
+}
+
+func undelete(ctx *cli.Context) error {
+	return changeVersions(ctx, "undeleted", "", true, (*api.KVv2).Undelete)
+}
+
+// changeVersions applies op to the versions given by --versions of every path.
+// If defaultToLatest is set and no versions are given, op is applied to the
+// current version of each path instead. Unless confirmVerb is empty, the user
+// has to confirm the operation once the paths are known.
+func changeVersions(ctx *cli.Context, verb, confirmVerb string, defaultToLatest bool, op func(kv *api.KVv2, ctx context.Context, path string, versions []int) error) error {
+	versions := ctx.IntSlice("versions")
+	if len(versions) == 0 && !defaultToLatest {
+		return errors.New("no versions given")
+	}
+	for _, version := range versions {
+		if version <= 0 {
+			return fmt.Errorf("invalid version %d", version)
+		}
+	}
+	client, err := createClient(ctx)
+	if err != nil {
+		return err
+	}
+	paths, err := readPaths(ctx)
+	if err != nil {
+		return err
+	}
+	if confirmVerb != "" {
+		if err := confirm(ctx, confirmVerb, len(paths)); err != nil {
+			return err
+		}
+	}
+	kv := client.KVv2(ctx.String("mount"))
+	result := mapConcurrently(ctx.Context, paths, func(path string) ([]int, error) {
+		pathVersions := versions
+		if len(pathVersions) == 0 {
+			meta, err := kv.GetMetadata(ctx.Context, path)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get metadata for %s: %w", path, err)
+			}
+			pathVersions = []int{meta.CurrentVersion}
+		}
+		if err := op(kv, ctx.Context, path, pathVersions); err != nil {
+			return nil, fmt.Errorf("failed to change versions %s of %s: %w", joinVersions(pathVersions), path, err)
+		}
+		return pathVersions, nil
+	})
+
+	errs := make([]error, 0)
+	for idx, r := range result {
+		if r.err != nil {
+			errs = append(errs, r.err)
+			continue
+		}
+		fmt.Printf("%s: %s versions %s\n", paths[idx], verb, joinVersions(r.value))
+	}
+	return joinErrors(errs, len(result)-len(errs))
+}
+
+func joinVersions(versions []int) string {
+	strs := make([]string, 0, len(versions))
+	for _, version := range versions {
+		strs = append(strs, strconv.Itoa(version))
+	}
+	return strings.Join(strs, ", ")
+}
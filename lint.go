@@ -0,0 +1,222 @@
+/******************************************************************************
+*
+*  Copyright 2024 SAP SE
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"sort"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/urfave/cli/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// metadataPolicy is the policy checked by kv lintmetas, read from a YAML or
+// JSON file.
+type metadataPolicy struct {
+	// keys every secret must have
+	Required []string `yaml:"required"`
+	// the values allowed for a key, keys without an entry may have any value
+	Allowed map[string][]string `yaml:"allowed"`
+	// values filled in for missing required keys with --fix
+	Defaults map[string]string `yaml:"defaults"`
+}
+
+// metadataViolation lists the problems of a secret as printed by kv lintmetas.
+type metadataViolation struct {
+	Path     string   `json:"path"     yaml:"path"`
+	Problems []string `json:"problems" yaml:"problems"`
+	// keys which were filled with their default by --fix
+	Fixed []string `json:"fixed,omitempty" yaml:"fixed,omitempty"`
+	// the missing keys which have a default
+	fixable    []string
+	customMeta map[string]any
+}
+
+func loadMetadataPolicy(file string) (metadataPolicy, error) {
+	var policy metadataPolicy
+	buf, err := os.ReadFile(file)
+	if err != nil {
+		return policy, fmt.Errorf("failed to read policy: %w", err)
+	}
+	if err := yaml.Unmarshal(buf, &policy); err != nil {
+		return policy, fmt.Errorf("failed to parse policy %s: %w", file, err)
+	}
+	for key, value := range policy.Defaults {
+		if allowed, ok := policy.Allowed[key]; ok && !slices.Contains(allowed, value) {
+			return policy, fmt.Errorf("the default %q for %s is not an allowed value", value, key)
+		}
+	}
+	return policy, nil
+}
+
+// check returns the problems of customMeta and the missing keys which have a
+// default value.
+func (p metadataPolicy) check(customMeta map[string]any) (problems, fixable []string) {
+	for _, key := range p.Required {
+		if _, ok := customMeta[key]; ok {
+			continue
+		}
+		if _, ok := p.Defaults[key]; ok {
+			fixable = append(fixable, key)
+		}
+		problems = append(problems, fmt.Sprintf("missing required key %s", key))
+	}
+	keys := make([]string, 0, len(p.Allowed))
+	for key := range p.Allowed {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value, ok := customMeta[key]
+		if ok && !slices.Contains(p.Allowed[key], fmt.Sprint(value)) {
+			problems = append(problems, fmt.Sprintf("value %q of %s is not allowed", fmt.Sprint(value), key))
+		}
+	}
+	return problems, fixable
+}
+
+func lintmetas(ctx *cli.Context) (err error) {
+	format := ctx.String("format")
+	if err := checkFormat(format, "text", "json", "yaml"); err != nil {
+		return err
+	}
+	policy, err := loadMetadataPolicy(ctx.String("policy"))
+	if err != nil {
+		return err
+	}
+	client, err := createClient(ctx)
+	if err != nil {
+		return err
+	}
+	out, err := openOutput(ctx)
+	if err != nil {
+		return err
+	}
+	defer closeOutput(out, &err)
+	kv := client.KVv2(ctx.String("mount"))
+	lister := newLister(ctx, client)
+	paths, err := lister.listSecretDirRecurse(ctx.Context, "/")
+	if err != nil {
+		return err
+	}
+	sort.Strings(paths)
+
+	result := mapConcurrently(ctx.Context, paths, func(path string) (metadataViolation, error) {
+		path = relativePath(path)
+		meta, err := kv.GetMetadata(ctx.Context, path)
+		if err != nil {
+			return metadataViolation{}, fmt.Errorf("failed to get metadata for %s: %w", path, err)
+		}
+		problems, fixable := policy.check(meta.CustomMetadata)
+		return metadataViolation{Path: path, Problems: problems, fixable: fixable, customMeta: meta.CustomMetadata}, nil
+	})
+	violations := make([]metadataViolation, 0)
+	errs := make([]error, 0)
+	fixCount := 0
+	for _, r := range result {
+		switch {
+		case r.err != nil:
+			errs = append(errs, r.err)
+		case len(r.value.Problems) > 0:
+			violations = append(violations, r.value)
+			if len(r.value.fixable) > 0 {
+				fixCount++
+			}
+		}
+	}
+
+	fix := ctx.Bool("fix")
+	if fix && fixCount > 0 {
+		if err := confirm(ctx, "fill in default custom metadata of", fixCount); err != nil {
+			return err
+		}
+		fixed := mapConcurrently(ctx.Context, violations, func(v metadataViolation) (metadataViolation, error) {
+			return fillDefaults(ctx.Context, kv, policy, v)
+		})
+		for idx, r := range fixed {
+			if r.err != nil {
+				errs = append(errs, r.err)
+				continue
+			}
+			violations[idx] = r.value
+		}
+	}
+	if err := printViolations(out, format, violations); err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		return joinErrors(errs, len(paths)-len(errs))
+	}
+	unfixed := 0
+	for _, v := range violations {
+		if len(v.Problems) > 0 {
+			unfixed++
+		}
+	}
+	if unfixed > 0 {
+		return fmt.Errorf("%d secret(s) violate the metadata policy", unfixed)
+	}
+	return nil
+}
+
+// fillDefaults adds the default values of the missing keys of a secret and
+// checks the result again.
+func fillDefaults(ctx context.Context, kv *api.KVv2, policy metadataPolicy, v metadataViolation) (metadataViolation, error) {
+	if len(v.fixable) == 0 {
+		return v, nil
+	}
+	patch := api.KVMetadataPatchInput{CustomMetadata: make(map[string]any, len(v.fixable))}
+	for _, key := range v.fixable {
+		patch.CustomMetadata[key] = policy.Defaults[key]
+	}
+	if err := kv.PatchMetadata(ctx, v.Path, patch); err != nil {
+		return v, fmt.Errorf("failed to update metadata for %s: %w", v.Path, err)
+	}
+	fixed := maps.Clone(v.customMeta)
+	if fixed == nil {
+		fixed = make(map[string]any)
+	}
+	maps.Copy(fixed, patch.CustomMetadata)
+	v.Problems, _ = policy.check(fixed)
+	v.Fixed = v.fixable
+	return v, nil
+}
+
+// printViolations prints the violations as "text" with one problem per line
+// or as a "json" or "yaml" array.
+func printViolations(w io.Writer, format string, violations []metadataViolation) error {
+	if format != "text" {
+		return encodeOutput(w, format, violations)
+	}
+	for _, v := range violations {
+		for _, key := range v.Fixed {
+			fmt.Fprintf(w, "%s: filled in default for %s\n", v.Path, key)
+		}
+		for _, problem := range v.Problems {
+			fmt.Fprintf(w, "%s: %s\n", v.Path, problem)
+		}
+	}
+	return nil
+}
@@ -19,22 +19,39 @@
 package main
 
 import (
+	"bufio"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
 	"net/http"
 	"os"
+	"os/signal"
+	"slices"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
 
 	"github.com/hashicorp/vault/api"
 	"github.com/sapcc/go-bits/vault"
 	"github.com/urfave/cli/v2"
 	"golang.org/x/sync/semaphore"
+	"gopkg.in/yaml.v3"
 )
 
-const concurrency int64 = 10
+const (
+	concurrency      int64 = 10
+	minRetryWait           = 500 * time.Millisecond
+	maxRetryWait           = 30 * time.Second
+	progressInterval       = 2 * time.Second
+	mountEnvVar            = "MUTAVAULT_MOUNT"
+)
 
 type Result[T any] struct {
 	value T
@@ -45,218 +62,2534 @@ func main() {
 	app := cli.App{
 		Name:  "mutavault",
 		Usage: "Additional utilities to interact with Hashicorp vault",
+		// path completion needs the completion script to pass the current word, see README
+		EnableBashCompletion: true,
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "namespace",
+				Usage: "Vault namespace to operate in, overrides VAULT_NAMESPACE",
+			},
+			&cli.IntFlag{
+				Name:    "max-retries",
+				Usage:   "Number of retries for requests failing with 429 or 5xx",
+				EnvVars: []string{"VAULT_MAX_RETRIES"},
+				Value:   3,
+			},
+			&cli.DurationFlag{
+				Name:  "timeout",
+				Usage: "Timeout for each request to vault, overrides VAULT_CLIENT_TIMEOUT",
+			},
+			&cli.StringFlag{
+				Name:  "ca-cert",
+				Usage: "PEM-encoded CA certificate file to verify the vault server with, in addition to VAULT_CACERT",
+			},
+			&cli.BoolFlag{
+				Name:  "tls-skip-verify",
+				Usage: "Do not verify the TLS certificate of the vault server, like VAULT_SKIP_VERIFY",
+			},
+			&cli.BoolFlag{
+				Name:    "verbose",
+				Aliases: []string{"v"},
+				Usage:   "Log every request to vault to stderr",
+			},
+			&cli.BoolFlag{
+				Name:  "stats",
+				Usage: "Print statistics about the requests to vault to stderr when done",
+			},
+			&cli.BoolFlag{
+				Name:  "no-token-renewal",
+				Usage: "Do not renew the token in the background while a command runs",
+			},
+			&cli.StringFlag{
+				Name:  "auth-method",
+				Usage: "Log in with approle, kubernetes, oidc or cert instead of using the token from VAULT_TOKEN or ~/.vault-token",
+				Value: "token",
+			},
+			&cli.StringFlag{
+				Name:  "auth-mount",
+				Usage: "Mount path of the auth method, defaults to the name of the method",
+			},
+			&cli.StringFlag{
+				Name:  "role",
+				Usage: "Role to log in with, required for kubernetes and optional for oidc and cert",
+			},
+			&cli.StringFlag{
+				Name:    "role-id",
+				Usage:   "Role ID for approle login",
+				EnvVars: []string{"VAULT_ROLE_ID"},
+			},
+			&cli.StringFlag{
+				Name:  "secret-id-file",
+				Usage: "File containing the secret ID for approle login, defaults to VAULT_SECRET_ID",
+			},
+			&cli.StringFlag{
+				Name:  "jwt-path",
+				Usage: "Service account token for kubernetes login",
+				Value: "/var/run/secrets/kubernetes.io/serviceaccount/token",
+			},
+			&cli.StringFlag{
+				Name:  "oidc-listen-address",
+				Usage: "Local address receiving the OIDC callback, the role must allow http://<address>/oidc/callback as redirect URI",
+				Value: "localhost:8250",
+			},
+			&cli.StringFlag{
+				Name:  "client-cert",
+				Usage: "PEM-encoded client certificate for TLS, e.g. for cert login, like VAULT_CLIENT_CERT",
+			},
+			&cli.StringFlag{
+				Name:  "client-key",
+				Usage: "PEM-encoded private key of --client-cert, like VAULT_CLIENT_KEY",
+			},
+		},
+		Before: setupLogging,
+		After:  printStats,
 		Commands: []*cli.Command{
 			{
 				Name:  "kv",
-				Usage: "Utilities for interacting with a kvv2 engine",
+				Usage: "Utilities for interacting with a kv engine",
 				Flags: []cli.Flag{
 					&cli.StringFlag{
-						Name:     "mount",
-						Usage:    "Mount path of kvv2 engine",
-						Required: true,
+						Name:    "mount",
+						Usage:   "Mount path of kv engine",
+						EnvVars: []string{mountEnvVar},
+					},
+					&cli.BoolFlag{
+						Name:  "all-mounts",
+						Usage: "Operate on all kvv2 engines instead of --mount, only supported by listall and getcustommetas",
+					},
+					&cli.IntFlag{
+						Name:  "kv-version",
+						Usage: "Version of the kv engine, either 1 or 2",
+						Value: 2,
 					},
 				},
+				Before: validateKVFlags,
 				Subcommands: []*cli.Command{
 					{
-						Name:   "listall",
-						Usage:  "List all accessible paths in a kv engine",
+						Name:  "listall",
+						Usage: "List all accessible paths in a kv engine",
+						Flags: append([]cli.Flag{
+							&cli.IntFlag{
+								Name:  "max-depth",
+								Usage: "Print directories deeper than this instead of descending into them, 0 means unlimited",
+							},
+							&cli.BoolFlag{
+								Name:  "progress",
+								Usage: "Periodically report progress to stderr",
+							},
+							&cli.StringFlag{
+								Name:  "filter",
+								Usage: "Only list secrets matching this glob, directories that cannot match are skipped",
+							},
+							&cli.StringFlag{
+								Name:  "regex",
+								Usage: "Only list secrets matching this regular expression",
+							},
+							&cli.StringSliceFlag{
+								Name:  "include",
+								Usage: "Only list secrets matching any of these globs, prefix a pattern with re: to use a regular expression",
+							},
+							&cli.StringSliceFlag{
+								Name:  "exclude",
+								Usage: "Skip secrets and directories matching any of these globs, prefix a pattern with re: to use a regular expression",
+							},
+							&cli.BoolFlag{
+								Name:  "count",
+								Usage: "Only print the number of secrets",
+							},
+							&cli.StringFlag{
+								Name:  "prefix",
+								Usage: "Only list the secrets below this directory",
+							},
+							&cli.BoolFlag{
+								Name:  "stream",
+								Usage: "Print paths as soon as they are found instead of sorting them, which keeps memory usage constant",
+							},
+							&cli.StringFlag{
+								Name:  "format",
+								Usage: "Output format, either text, tree or ndjson, which prints one JSON object per line and implies --stream, or json with --details",
+								Value: "text",
+							},
+							&cli.StringFlag{
+								Name:  "checkpoint",
+								Usage: "Periodically save the traversal state to this file and resume from it, it is removed once the listing is complete",
+							},
+							&cli.StringFlag{
+								Name:  "sort",
+								Usage: "Sort the paths by either path or updated_time, which fetches the metadata of each secret",
+								Value: "path",
+							},
+							&cli.BoolFlag{
+								Name:  "details",
+								Usage: "Fetch the metadata of each secret and print its creation and update time and versions",
+							},
+							&cli.StringFlag{
+								Name:  "modified-since",
+								Usage: "Only list secrets updated after this RFC 3339 timestamp or duration ago, e.g. 168h",
+							},
+							&cli.StringFlag{
+								Name:  "modified-before",
+								Usage: "Only list secrets last updated before this RFC 3339 timestamp or duration ago",
+							},
+							&cli.BoolFlag{
+								Name:  "strict",
+								Usage: "Abort instead of skipping directories with an unexpected list response",
+							},
+							&cli.StringFlag{
+								Name:  "forbidden",
+								Usage: "Handling of forbidden directories, either fail, skip with a warning or report them as JSON on stderr at the end",
+								Value: "skip",
+							},
+						}, outputFlags()...),
 						Action: listall,
 					},
 					{
-						Name:   "getcustommetas",
-						Usage:  "Gets the custom metadata of provided paths to secrets",
-						Args:   true,
-						Action: getcustommetas,
+						Name:  "tree",
+						Usage: "Show all accessible paths in a kv engine as a tree",
+						Flags: append([]cli.Flag{
+							&cli.IntFlag{
+								Name:  "max-depth",
+								Usage: "Do not descend into directories deeper than this, 0 means unlimited",
+							},
+							&cli.BoolFlag{
+								Name:  "strict",
+								Usage: "Abort instead of skipping directories with an unexpected list response",
+							},
+							&cli.StringFlag{
+								Name:  "forbidden",
+								Usage: "Handling of forbidden directories, either fail, skip with a warning or report them as JSON on stderr at the end",
+								Value: "skip",
+							},
+						}, outputFlags()...),
+						Action: tree,
+					},
+					{
+						Name:      "getcustommetas",
+						Usage:     "Gets the custom metadata of provided paths to secrets",
+						Args:      true,
+						ArgsUsage: "<path>... or - to read newline-delimited paths from stdin",
+						Flags: append([]cli.Flag{
+							&cli.BoolFlag{
+								Name:  "stdin",
+								Usage: "Read newline-delimited paths from stdin instead of arguments",
+							},
+							&cli.StringFlag{
+								Name:  "path-key",
+								Usage: "Key under which the path is added to each object",
+								Value: "path",
+							},
+							&cli.StringFlag{
+								Name:  "updated-time-key",
+								Usage: "Key under which the updated time of the metadata is added to each object, for setcustommetas --updated-time-key",
+							},
+							&cli.BoolFlag{
+								Name:  "ndjson",
+								Usage: "Print each object on its own line as soon as it is fetched instead of a single array",
+							},
+							&cli.StringFlag{
+								Name:  "sort",
+								Usage: "Sort the objects by path, updated_time or none, defaults to none with --ndjson to stream the objects as they are fetched",
+								Value: "path",
+							},
+							&cli.StringFlag{
+								Name:  "format",
+								Usage: "Output format, either json, yaml or csv",
+								Value: "json",
+							},
+						}, outputFlags()...),
+						Before:       requireKVv2,
+						BashComplete: completePaths,
+						Action:       getcustommetas,
+					},
+					{
+						Name:      "get",
+						Aliases:   []string{"getall"},
+						Usage:     "Gets the data of provided paths to secrets",
+						ArgsUsage: "<path>... or - to read newline-delimited paths from stdin",
+						Flags: append([]cli.Flag{
+							&cli.IntFlag{
+								Name:  "version",
+								Usage: "Version of the secrets to get, 0 means the latest version",
+							},
+							&cli.BoolFlag{
+								Name:  "stdin",
+								Usage: "Read newline-delimited paths from stdin instead of arguments",
+							},
+							&cli.BoolFlag{
+								Name:  "ndjson",
+								Usage: "Print each secret on its own line instead of a single array",
+							},
+						}, outputFlags()...),
+						BashComplete: completePaths,
+						Action:       get,
 					},
 					{
-						Name:   "setcustommetas",
-						Usage:  "Takes custommetadata and paths on stdin and updates vault",
+						Name:   "get-version",
+						Usage:  "Takes paths and versions on stdin and gets the data of exactly these versions",
+						Flags:  outputFlags(),
+						Before: requireKVv2,
+						Action: getVersion,
+					},
+					{
+						Name:    "put",
+						Aliases: []string{"putall"},
+						Usage:   "Takes paths and data on stdin and writes them as new secret versions",
+						Flags: []cli.Flag{
+							&cli.IntFlag{
+								Name:  "cas",
+								Usage: "Only write if the current version of each secret matches, 0 only allows creating new secrets",
+							},
+						},
+						Before: requireKVv2,
+						Action: put,
+					},
+					{
+						Name:      "patch",
+						Usage:     "Takes a JSON object on stdin and merges it into the data of a secret as a new version",
+						ArgsUsage: "<path>",
+						Flags: []cli.Flag{
+							&cli.IntFlag{
+								Name:  "cas",
+								Usage: "Only patch if the current version of the secret matches",
+							},
+						},
+						Before:       requireKVv2,
+						BashComplete: completePaths,
+						Action:       patch,
+					},
+					{
+						Name:  "setcustommetas",
+						Usage: "Takes custommetadata and paths on stdin and updates vault",
+						Flags: []cli.Flag{
+							&cli.BoolFlag{
+								Name:  "fail-fast",
+								Usage: "Stop at the first failed update instead of processing all entries",
+							},
+							&cli.StringFlag{
+								Name:  "path-key",
+								Usage: "Key from which the path is read in each object",
+								Value: "path",
+							},
+							&cli.StringFlag{
+								Name:  "format",
+								Usage: "Input format, either json, yaml or csv",
+								Value: "json",
+							},
+							&cli.BoolFlag{
+								Name:  "strict",
+								Usage: "Fail instead of warning when no objects are given on stdin",
+							},
+							&cli.BoolFlag{
+								Name:  "report",
+								Usage: "Print the added, removed and changed custom metadata keys of each updated path as JSON",
+							},
+							&cli.BoolFlag{
+								Name:  "no-existence-check",
+								Usage: "Write without reading the metadata of each secret first, halving the requests for trusted input",
+							},
+							&cli.BoolFlag{
+								Name:  "last-wins",
+								Usage: "Allow multiple objects with the same path and only apply the last one",
+							},
+							&cli.StringFlag{
+								Name:  "schema",
+								Usage: "JSON Schema file the custom metadata of each object is validated against before anything is written",
+							},
+							&cli.StringFlag{
+								Name:  "template",
+								Usage: "Instead of reading stdin, set key=template,... for each secret matching --match, e.g. 'team={{ index .PathParts 1 }}', implies --merge",
+							},
+							&cli.StringFlag{
+								Name:  "match",
+								Usage: "Glob selecting the secrets updated with --template, e.g. 'teams/*/*/**'",
+							},
+							&cli.BoolFlag{
+								Name:  "dry-run",
+								Usage: "Only print the changes of each path like --report without writing anything",
+							},
+							&cli.StringFlag{
+								Name:  "diff-format",
+								Usage: "Format of the changes printed by --report and --dry-run, either json or text",
+								Value: "json",
+							},
+							&cli.BoolFlag{
+								Name:  "merge",
+								Usage: "Only set the given custom metadata keys and keep all others",
+							},
+							&cli.StringSliceFlag{
+								Name:  "delete-key",
+								Usage: "Remove this custom metadata key from each secret, can be repeated",
+							},
+							&cli.StringFlag{
+								Name:  "updated-time-key",
+								Usage: "Key holding the updated time emitted by getcustommetas, writes are refused if the metadata was changed since",
+							},
+							&cli.BoolFlag{
+								Name:  "force",
+								Usage: "Write the metadata even if it was changed since getcustommetas read it",
+							},
+							&cli.IntFlag{
+								Name:  "concurrency",
+								Usage: "Number of secrets updated in parallel",
+								Value: int(concurrency),
+							},
+							yesFlag(),
+						},
+						Before: requireKVv2,
 						Action: setcustommetas,
 					},
+					{
+						Name:  "export",
+						Usage: "Exports the latest version and metadata of all secrets as newline-delimited JSON",
+						Flags: append([]cli.Flag{
+							&cli.BoolFlag{
+								Name:  "all-versions",
+								Usage: "Also export all previous versions of each secret for a full backup",
+							},
+						}, outputFlags()...),
+						Before: requireKVv2,
+						Action: export,
+					},
+					{
+						Name:  "import",
+						Usage: "Restores secrets and their metadata from the output of export",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:  "input",
+								Usage: "File to read the export from instead of stdin",
+							},
+							&cli.BoolFlag{
+								Name:  "skip-existing",
+								Usage: "Do not overwrite secrets that already exist",
+							},
+							&cli.BoolFlag{
+								Name:  "overwrite",
+								Usage: "Remove secrets that already exist including all their versions before restoring them",
+							},
+						},
+						Before: requireKVv2,
+						Action: importSecrets,
+					},
+					{
+						Name:  "search",
+						Usage: "Lists all paths whose custom metadata matches the given key and value",
+						Flags: append([]cli.Flag{
+							&cli.StringFlag{
+								Name:     "key",
+								Usage:    "Custom metadata key to match",
+								Required: true,
+							},
+							&cli.StringFlag{
+								Name:  "value",
+								Usage: "Custom metadata value to match, matches any value if unset",
+							},
+							&cli.BoolFlag{
+								Name:  "regex",
+								Usage: "Treat --value as a regular expression",
+							},
+						}, outputFlags()...),
+						Before: requireKVv2,
+						Action: search,
+					},
+					{
+						Name:      "copy",
+						Usage:     "Copies the latest version and custom metadata of a secret to another path",
+						ArgsUsage: "<src> <dst>",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:   "src-mount",
+								Usage:  "Mount path of the source kvv2 engine, defaults to --mount",
+								Action: normalizeMountFlag("src-mount"),
+							},
+							&cli.StringFlag{
+								Name:   "dst-mount",
+								Usage:  "Mount path of the destination kvv2 engine, defaults to the source mount",
+								Action: normalizeMountFlag("dst-mount"),
+							},
+							&cli.BoolFlag{
+								Name:  "overwrite",
+								Usage: "Overwrite the destination if it already exists",
+							},
+							&cli.BoolFlag{
+								Name:  "all-versions",
+								Usage: "Copy all versions which have not been deleted or destroyed instead of only the latest one",
+							},
+							&cli.BoolFlag{
+								Name:  "recursive",
+								Usage: "Treat the paths as directories and copy all secrets below the source",
+							},
+						},
+						Before:       requireKVv2,
+						BashComplete: completePaths,
+						Action:       copySecret,
+					},
+					{
+						Name:      "versions",
+						Usage:     "Lists the version history of provided paths to secrets",
+						ArgsUsage: "<path>...",
+						Flags: append([]cli.Flag{
+							&cli.StringFlag{
+								Name:  "format",
+								Usage: "Output format, either table, json or yaml",
+								Value: "table",
+							},
+						}, outputFlags()...),
+						Before:       requireKVv2,
+						BashComplete: completePaths,
+						Action:       versions,
+					},
+					{
+						Name:      "rollback",
+						Usage:     "Writes the data of a previous version of a secret as a new version",
+						ArgsUsage: "<path>",
+						Flags: []cli.Flag{
+							&cli.IntFlag{
+								Name:     "to-version",
+								Usage:    "Version to restore",
+								Required: true,
+							},
+							&cli.BoolFlag{
+								Name:  "dry-run",
+								Usage: "Only print which version would be restored",
+							},
+						},
+						Before:       requireKVv2,
+						BashComplete: completePaths,
+						Action:       rollback,
+					},
+					{
+						Name:      "tag",
+						Usage:     "Sets a custom metadata key of provided paths to secrets",
+						ArgsUsage: "<path>...",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:     "key",
+								Usage:    "Custom metadata key to set",
+								Required: true,
+							},
+							&cli.StringFlag{
+								Name:     "value",
+								Usage:    "Value to set the key to",
+								Required: true,
+							},
+							&cli.BoolFlag{
+								Name:  "recursive",
+								Usage: "Treat the paths as directories and apply to all secrets below them",
+							},
+						},
+						Before:       requireKVv2,
+						BashComplete: completePaths,
+						Action:       tag,
+					},
+					{
+						Name:      "delete-metadata-keys",
+						Aliases:   []string{"untag"},
+						Usage:     "Removes the given keys from the custom metadata of provided paths to secrets",
+						ArgsUsage: "<path>...",
+						Flags: []cli.Flag{
+							&cli.StringSliceFlag{
+								Name:     "key",
+								Usage:    "Custom metadata key to remove, can be repeated",
+								Required: true,
+							},
+							&cli.BoolFlag{
+								Name:  "recursive",
+								Usage: "Treat the paths as directories and apply to all secrets below them",
+							},
+						},
+						Before:       requireKVv2,
+						BashComplete: completePaths,
+						Action:       deleteMetadataKeys,
+					},
+					{
+						Name:      "move",
+						Aliases:   []string{"rename"},
+						Usage:     "Moves the latest version and metadata of a secret to another path and deletes the source",
+						ArgsUsage: "<src> <dst>",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:   "src-mount",
+								Usage:  "Mount path of the source kvv2 engine, defaults to --mount",
+								Action: normalizeMountFlag("src-mount"),
+							},
+							&cli.StringFlag{
+								Name:   "dst-mount",
+								Usage:  "Mount path of the destination kvv2 engine, defaults to the source mount",
+								Action: normalizeMountFlag("dst-mount"),
+							},
+							&cli.BoolFlag{
+								Name:  "overwrite",
+								Usage: "Overwrite the destination if it already exists",
+							},
+							&cli.BoolFlag{
+								Name:  "all-versions",
+								Usage: "Move all versions which have not been deleted or destroyed instead of only the latest one",
+							},
+							&cli.BoolFlag{
+								Name:  "recursive",
+								Usage: "Treat the paths as directories and move all secrets below the source",
+							},
+							&cli.BoolFlag{
+								Name:  "soft-delete",
+								Usage: "Only soft-delete the latest version of the source instead of removing all its versions and metadata",
+							},
+							yesFlag(),
+						},
+						Before:       requireKVv2,
+						BashComplete: completePaths,
+						Action:       moveSecret,
+					},
+					{
+						Name:      "diff",
+						Usage:     "Shows the differences between the data and custom metadata of two versions or two secrets",
+						ArgsUsage: "<path> or <pathA> <pathB>",
+						Flags: []cli.Flag{
+							&cli.IntFlag{
+								Name:  "version-a",
+								Usage: "Version of the first secret, defaults to the latest version",
+							},
+							&cli.IntFlag{
+								Name:  "version-b",
+								Usage: "Version of the second secret, defaults to the latest version",
+							},
+							&cli.StringFlag{
+								Name:   "mount-b",
+								Usage:  "Mount path of the kvv2 engine containing pathB, defaults to --mount",
+								Action: normalizeMountFlag("mount-b"),
+							},
+							&cli.BoolFlag{
+								Name:  "show-values",
+								Usage: "Print the differing values of the secret data instead of only the keys",
+							},
+							&cli.BoolFlag{
+								Name:  "exit-code",
+								Usage: "Exit with status 4 if there are differences",
+							},
+						},
+						Before:       requireKVv2,
+						BashComplete: completePaths,
+						Action:       diffSecrets,
+					},
+					{
+						Name:      "diff-metadata",
+						Usage:     "Shows the differences between the custom metadata of two secrets",
+						ArgsUsage: "<pathA> <pathB>",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:   "mount-b",
+								Usage:  "Mount path of the kvv2 engine containing pathB, defaults to --mount",
+								Action: normalizeMountFlag("mount-b"),
+							},
+							&cli.BoolFlag{
+								Name:  "exit-code",
+								Usage: "Exit with status 4 if there are differences",
+							},
+						},
+						Before:       requireKVv2,
+						BashComplete: completePaths,
+						Action:       diffmetadata,
+					},
+					{
+						Name:  "audit",
+						Usage: "Lists all secrets missing one of the required custom metadata keys",
+						Flags: append([]cli.Flag{
+							&cli.StringSliceFlag{
+								Name:     "require",
+								Usage:    "Custom metadata key every secret must have, can be repeated",
+								Required: true,
+							},
+							&cli.BoolFlag{
+								Name:  "exit-code",
+								Usage: "Exit with status 4 if a secret is missing a required key",
+							},
+							&cli.BoolFlag{
+								Name:  "progress",
+								Usage: "Periodically report progress to stderr",
+							},
+						}, outputFlags()...),
+						Before: requireKVv2,
+						Action: audit,
+					},
+					{
+						Name:      "destroy",
+						Usage:     "Permanently destroys versions of provided paths to secrets",
+						ArgsUsage: "<path>...",
+						Flags: []cli.Flag{
+							&cli.IntSliceFlag{
+								Name:     "versions",
+								Usage:    "Comma-separated list of versions",
+								Required: true,
+							},
+							yesFlag(),
+						},
+						Before:       requireKVv2,
+						BashComplete: completePaths,
+						Action:       destroy,
+					},
+					{
+						Name:      "undelete",
+						Usage:     "Restores soft-deleted versions of provided paths to secrets",
+						ArgsUsage: "<path>... or - to read newline-delimited paths from stdin",
+						Flags: []cli.Flag{
+							&cli.IntSliceFlag{
+								Name:  "versions",
+								Usage: "Comma-separated list of versions, defaults to the current version of each secret",
+							},
+							&cli.BoolFlag{
+								Name:  "stdin",
+								Usage: "Read newline-delimited paths from stdin instead of arguments",
+							},
+						},
+						Before:       requireKVv2,
+						BashComplete: completePaths,
+						Action:       undelete,
+					},
+					{
+						Name:      "stat",
+						Usage:     "Summarizes the versions and settings of provided paths to secrets",
+						ArgsUsage: "<path>...",
+						Flags: append([]cli.Flag{
+							&cli.StringFlag{
+								Name:  "format",
+								Usage: "Output format, either table, json or yaml",
+								Value: "table",
+							},
+						}, outputFlags()...),
+						Before:       requireKVv2,
+						BashComplete: completePaths,
+						Action:       stat,
+					},
+					{
+						Name:      "deleteall",
+						Usage:     "Deletes all secrets matching the given glob patterns or paths on stdin",
+						ArgsUsage: "<glob>...",
+						Flags: []cli.Flag{
+							&cli.BoolFlag{
+								Name:  "stdin",
+								Usage: "Read newline-delimited paths from stdin instead of glob patterns",
+							},
+							&cli.BoolFlag{
+								Name:  "destroy",
+								Usage: "Permanently destroy all versions instead of soft-deleting the latest version",
+							},
+							&cli.BoolFlag{
+								Name:  "metadata",
+								Usage: "Remove all versions and the metadata instead of soft-deleting the latest version",
+							},
+							&cli.BoolFlag{
+								Name:  "dry-run",
+								Usage: "Only print the secrets which would be deleted",
+							},
+							yesFlag(),
+						},
+						Before: requireKVv2,
+						Action: deleteAll,
+					},
+					{
+						Name:      "prune-versions",
+						Usage:     "Destroys old versions of all secrets below the given directories",
+						ArgsUsage: "[<dir>...]",
+						Flags: []cli.Flag{
+							&cli.IntFlag{
+								Name:  "keep",
+								Usage: "Number of newest versions to keep of each secret",
+							},
+							&cli.StringFlag{
+								Name:  "older-than",
+								Usage: "Only destroy versions created longer ago than this, e.g. 90d or 2160h",
+							},
+							&cli.BoolFlag{
+								Name:  "dry-run",
+								Usage: "Only print the versions which would be destroyed",
+							},
+							yesFlag(),
+						},
+						Before: requireKVv2,
+						Action: pruneVersions,
+					},
+					{
+						Name:      "sync",
+						Usage:     "Copies secrets below the given directories whose data or custom metadata differ to another mount or vault",
+						ArgsUsage: "[<dir>...]",
+						Flags: append([]cli.Flag{
+							&cli.BoolFlag{
+								Name:  "dry-run",
+								Usage: "Only print the secrets which would be synced",
+							},
+						}, syncFlags()...),
+						Before: requireKVv2,
+						Action: syncSecrets,
+					},
+					{
+						Name:      "mirror",
+						Usage:     "Like sync, but keeps replicating changes of the source mount as they happen",
+						ArgsUsage: "[<dir>...]",
+						Flags: append([]cli.Flag{
+							&cli.BoolFlag{
+								Name:  "follow",
+								Usage: "Keep running and mirror each change reported by the event notifications of vault 1.16 or newer",
+							},
+						}, syncFlags()...),
+						Before: requireKVv2,
+						Action: mirror,
+					},
+					{
+						Name:  "migrate-v1",
+						Usage: "Copies all secrets of the kvv1 engine at --mount into a kvv2 engine",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:     "dst-mount",
+								Usage:    "Mount path of the destination kvv2 engine",
+								Action:   normalizeMountFlag("dst-mount"),
+								Required: true,
+							},
+							&cli.StringFlag{
+								Name:  "stamp",
+								Usage: "Custom metadata key to record the time of the migration under, e.g. migrated_at",
+							},
+							&cli.BoolFlag{
+								Name:  "skip-existing",
+								Usage: "Do not overwrite secrets that already exist in the destination",
+							},
+						},
+						Action: migrateV1,
+					},
+					{
+						Name:   "mounts",
+						Usage:  "List the paths of all kvv2 engines visible to the token",
+						Flags:  outputFlags(),
+						Action: listMounts,
+					},
+					{
+						Name:  "lintmetas",
+						Usage: "Reports all secrets whose custom metadata violates a policy of required keys and allowed values",
+						Flags: append([]cli.Flag{
+							&cli.StringFlag{
+								Name:     "policy",
+								Usage:    "YAML or JSON file with the required keys, allowed values and defaults",
+								Required: true,
+							},
+							&cli.BoolFlag{
+								Name:  "fix",
+								Usage: "Fill in the defaults of the policy for missing required keys",
+							},
+							&cli.StringFlag{
+								Name:  "format",
+								Usage: "Output format, either text, json or yaml",
+								Value: "text",
+							},
+							yesFlag(),
+						}, outputFlags()...),
+						Before: requireKVv2,
+						Action: lintmetas,
+					},
+					{
+						Name:  "validatemetas",
+						Usage: "Validates custom metadata on stdin like setcustommetas without writing it",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:  "schema",
+								Usage: "JSON Schema file the custom metadata of each object is validated against",
+							},
+							&cli.StringFlag{
+								Name:  "path-key",
+								Usage: "Key from which the path is read in each object",
+								Value: "path",
+							},
+							&cli.StringFlag{
+								Name:  "format",
+								Usage: "Input format, either json, yaml or csv",
+								Value: "json",
+							},
+							&cli.BoolFlag{
+								Name:  "strict",
+								Usage: "Fail instead of warning when no objects are given on stdin",
+							},
+							&cli.BoolFlag{
+								Name:  "last-wins",
+								Usage: "Allow multiple objects with the same path",
+							},
+						},
+						Action: validatemetas,
+					},
+					{
+						Name:  "findmetas",
+						Usage: "Lists all paths whose custom metadata matches an expression",
+						Flags: append([]cli.Flag{
+							&cli.StringFlag{
+								Name:     "where",
+								Usage:    `Expression over the custom metadata, e.g. 'owner == "team-x" && env != "prod"'`,
+								Required: true,
+							},
+							&cli.BoolFlag{
+								Name:  "full",
+								Usage: "Print the custom metadata of each match like getcustommetas instead of only the path",
+							},
+							&cli.StringFlag{
+								Name:  "path-key",
+								Usage: "Key under which the path is added to each object with --full",
+								Value: "path",
+							},
+							&cli.StringFlag{
+								Name:  "format",
+								Usage: "Output format with --full, either json or yaml",
+								Value: "json",
+							},
+						}, outputFlags()...),
+						Before: requireKVv2,
+						Action: findmetas,
+					},
+					{
+						Name:      "setmetaconfig",
+						Usage:     "Sets max_versions, delete_version_after and cas_required of the given secrets",
+						Args:      true,
+						ArgsUsage: "<path or glob>... or - to read newline-delimited paths from stdin",
+						Flags: []cli.Flag{
+							&cli.IntFlag{
+								Name:  "max-versions",
+								Usage: "Number of versions to keep, 0 uses the mount default",
+							},
+							&cli.DurationFlag{
+								Name:  "delete-version-after",
+								Usage: "Delete versions after this duration, 0 disables it",
+							},
+							&cli.BoolFlag{
+								Name:  "cas-required",
+								Usage: "Require check-and-set for writes, pass --cas-required=false to disable it",
+							},
+							&cli.BoolFlag{
+								Name:  "stdin",
+								Usage: "Read newline-delimited paths from stdin instead of arguments",
+							},
+							&cli.BoolFlag{
+								Name:  "dry-run",
+								Usage: "Only print the changes without writing anything",
+							},
+							yesFlag(),
+						},
+						Before:       requireKVv2,
+						BashComplete: completePaths,
+						Action:       setmetaconfig,
+					},
+					{
+						Name:  "stats",
+						Usage: "Aggregates the number of secrets and versions and their update times per directory",
+						Flags: append([]cli.Flag{
+							&cli.IntFlag{
+								Name:  "depth",
+								Usage: "Number of path segments the directories are grouped by",
+								Value: 1,
+							},
+							&cli.StringFlag{
+								Name:  "format",
+								Usage: "Output format, either table, json or yaml",
+								Value: "table",
+							},
+						}, outputFlags()...),
+						Before: requireKVv2,
+						Action: kvStats,
+					},
+					{
+						Name:  "orphans",
+						Usage: "Lists all secrets whose versions are all deleted or destroyed",
+						Flags: append([]cli.Flag{
+							&cli.BoolFlag{
+								Name:  "purge",
+								Usage: "Delete the metadata of the listed secrets",
+							},
+							yesFlag(),
+						}, outputFlags()...),
+						Before: requireKVv2,
+						Action: orphans,
+					},
+					{
+						Name:  "stale",
+						Usage: "Lists all secrets whose latest version is older than a threshold",
+						Flags: append([]cli.Flag{
+							&cli.StringFlag{
+								Name:     "older-than",
+								Usage:    "Age of the latest version from which a secret is stale, e.g. 180d or 2160h",
+								Required: true,
+							},
+							&cli.StringFlag{
+								Name:  "owner-key",
+								Usage: "Custom metadata key whose value is printed as the owner",
+								Value: "owner",
+							},
+							&cli.StringFlag{
+								Name:  "format",
+								Usage: "Output format, either table, json or yaml",
+								Value: "table",
+							},
+						}, outputFlags()...),
+						Before: requireKVv2,
+						Action: stale,
+					},
+					{
+						Name:  "expire",
+						Usage: "Reports and optionally deletes or destroys all secrets past the expiry in their custom metadata",
+						Flags: append([]cli.Flag{
+							&cli.StringFlag{
+								Name:  "key",
+								Usage: "Custom metadata key holding the expiry as an RFC 3339 timestamp or a date",
+								Value: "expires-at",
+							},
+							&cli.StringFlag{
+								Name:  "action",
+								Usage: "Action for expired secrets, either warn, delete (soft-delete the latest version) or destroy (destroy all versions)",
+								Value: "warn",
+							},
+							&cli.StringFlag{
+								Name:  "format",
+								Usage: "Output format, either table, json or yaml",
+								Value: "table",
+							},
+							yesFlag(),
+						}, outputFlags()...),
+						Before: requireKVv2,
+						Action: expire,
+					},
+					{
+						Name:  "dupes",
+						Usage: "Lists groups of secrets with identical data by comparing hashes, without printing any values",
+						Flags: append([]cli.Flag{
+							&cli.BoolFlag{
+								Name:  "per-key",
+								Usage: "Compare each value on its own to also find credentials copied under another key",
+							},
+							&cli.StringFlag{
+								Name:  "format",
+								Usage: "Output format, either text, json or yaml",
+								Value: "text",
+							},
+						}, outputFlags()...),
+						Action: dupes,
+					},
+					{
+						Name:      "grep",
+						Usage:     "Lists the keys of all secrets whose name or value matches a regular expression",
+						Args:      true,
+						ArgsUsage: "<regex>",
+						Flags: append([]cli.Flag{
+							&cli.StringFlag{
+								Name:  "prefix",
+								Usage: "Only search the secrets below this directory",
+							},
+							&cli.BoolFlag{
+								Name:  "keys-only",
+								Usage: "Only match key names",
+							},
+							&cli.BoolFlag{
+								Name:  "values-only",
+								Usage: "Only match values",
+							},
+							&cli.BoolFlag{
+								Name:  "show-values",
+								Usage: "Print the values of the matching keys, which are redacted by default",
+							},
+						}, outputFlags()...),
+						Action: grep,
+					},
+					{
+						Name:      "verify",
+						Usage:     "Checks that all given secrets exist and are readable, optionally with specific keys",
+						Args:      true,
+						ArgsUsage: "<path>... or - to read newline-delimited paths from stdin",
+						Flags: append([]cli.Flag{
+							&cli.StringSliceFlag{
+								Name:  "require-key",
+								Usage: "Key that each secret must contain, can be repeated",
+							},
+							&cli.BoolFlag{
+								Name:  "stdin",
+								Usage: "Read newline-delimited paths from stdin instead of arguments",
+							},
+							&cli.StringFlag{
+								Name:  "format",
+								Usage: "Output format, either text, json or yaml",
+								Value: "text",
+							},
+						}, outputFlags()...),
+						BashComplete: completePaths,
+						Action:       verify,
+					},
+					{
+						Name:      "capabilities",
+						Usage:     "Shows which operations the current token may perform on the given secrets",
+						Args:      true,
+						ArgsUsage: "<path>... or - to read newline-delimited paths (e.g. from listall) from stdin",
+						Flags: append([]cli.Flag{
+							&cli.BoolFlag{
+								Name:  "stdin",
+								Usage: "Read newline-delimited paths from stdin instead of arguments",
+							},
+							&cli.IntFlag{
+								Name:  "batch-size",
+								Usage: "Number of paths to query per request to sys/capabilities-self",
+								Value: 100,
+							},
+							&cli.StringFlag{
+								Name:  "format",
+								Usage: "Output format, either text, json or yaml",
+								Value: "text",
+							},
+						}, outputFlags()...),
+						BashComplete: completePaths,
+						Action:       capabilities,
+					},
+				},
+			},
+			{
+				Name:  "policy",
+				Usage: "Utilities for analyzing ACL policies against the secrets of a kv engine",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:    "mount",
+						Usage:   "Mount path of kv engine",
+						EnvVars: []string{mountEnvVar},
+					},
+					&cli.IntFlag{
+						Name:  "kv-version",
+						Usage: "Version of the kv engine, either 1 or 2",
+						Value: 2,
+					},
+				},
+				Before: validatePolicyFlags,
+				Subcommands: []*cli.Command{
+					{
+						Name:      "coverage",
+						Usage:     "Shows which ACL policies apply to each of the given secrets",
+						Args:      true,
+						ArgsUsage: "<path>... or - to read newline-delimited paths (e.g. from listall) from stdin",
+						Flags: append([]cli.Flag{
+							&cli.BoolFlag{
+								Name:  "stdin",
+								Usage: "Read newline-delimited paths from stdin instead of arguments",
+							},
+							&cli.StringFlag{
+								Name:  "capability",
+								Usage: "Only show policies granting this capability, e.g. read or list",
+							},
+							&cli.StringFlag{
+								Name:  "format",
+								Usage: "Output format, either text, json or yaml",
+								Value: "text",
+							},
+						}, outputFlags()...),
+						Action: policyCoverage,
+					},
+					{
+						Name:      "generate",
+						Usage:     "Generates a policy granting the given capabilities on the given secrets",
+						Args:      true,
+						ArgsUsage: "<path>... or - to read newline-delimited paths (e.g. from listall) from stdin",
+						Flags: append([]cli.Flag{
+							&cli.BoolFlag{
+								Name:  "stdin",
+								Usage: "Read newline-delimited paths from stdin instead of arguments",
+							},
+							&cli.StringSliceFlag{
+								Name:  "capability",
+								Usage: "Capability to grant, one of create, read, update, patch, delete or list, can be repeated",
+								Value: cli.NewStringSlice("read"),
+							},
+							&cli.BoolFlag{
+								Name:  "collapse",
+								Usage: "List the kv engine and replace paths by + and * globs where no other secret would match",
+							},
+							&cli.StringFlag{
+								Name:  "format",
+								Usage: "Output format, either hcl or json",
+								Value: "hcl",
+							},
+						}, outputFlags()...),
+						Action: policyGenerate,
+					},
 				},
 			},
+			{
+				Name:   "whoami",
+				Usage:  "Prints the accessor, policies, TTL, entity and namespace of the current token as JSON",
+				Action: whoami,
+			},
 		},
 	}
-	if err := app.Run(os.Args); err != nil {
-		fmt.Fprintf(os.Stderr, "error: %s\n", err)
-		os.Exit(1)
+	// cancel the context on SIGINT and SIGTERM so that in-flight requests unwind
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	err := app.RunContext(ctx, os.Args)
+	interrupted := ctx.Err() != nil
+	stop()
+	if interrupted {
+		fmt.Fprintln(os.Stderr, "error: interrupted, pending operations were aborted")
+		os.Exit(exitInterrupted)
+	}
+	if err != nil && !errors.Is(err, errNoResults) && !errors.Is(err, errDifferencesFound) {
+		fmt.Fprintf(os.Stderr, "error: %s\n", err)
+		if renewErr := tokenRenewal.failure(); renewErr != nil {
+			fmt.Fprintf(os.Stderr, "error: %s, which might have caused the failure above\n", renewErr)
+		}
+	}
+	os.Exit(exitCode(err))
+}
+
+// createClient creates a vault client and applies the global client flags.
+func createClient(ctx *cli.Context) (*api.Client, error) {
+	method := ctx.String("auth-method")
+	if !slices.Contains(authMethods, method) {
+		return nil, fmt.Errorf("unsupported auth method %q", method)
+	}
+	var client *api.Client
+	var err error
+	if method == "token" {
+		client, err = vault.CreateClient()
+	} else {
+		client, err = newLoginClient()
+	}
+	if err != nil {
+		return nil, err
+	}
+	client, err = configureClient(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+	// log in only now so that the TLS, namespace and retry settings apply
+	if method != "token" {
+		if err := login(ctx, client); err != nil {
+			return nil, err
+		}
+	}
+	if !ctx.Bool("no-token-renewal") {
+		tokenRenewal.start(ctx.Context, client)
+	}
+	return client, nil
+}
+
+// configureClient applies the TLS, logging, namespace, timeout and retry flags
+// to client. The returned client replaces the given one.
+func configureClient(ctx *cli.Context, client *api.Client) (*api.Client, error) {
+	var err error
+	tlsConfig := api.TLSConfig{
+		CACert:     ctx.String("ca-cert"),
+		ClientCert: ctx.String("client-cert"),
+		ClientKey:  ctx.String("client-key"),
+		Insecure:   ctx.Bool("tls-skip-verify"),
+	}
+	configureTLS := tlsConfig.CACert != "" || tlsConfig.ClientCert != "" || tlsConfig.Insecure
+	if configureTLS || ctx.Bool("verbose") || ctx.Bool("stats") {
+		client, err = reconfigureClient(client, func(config *api.Config) error {
+			// the TLS settings can only be applied before the transport is wrapped
+			if configureTLS {
+				if err := config.ConfigureTLS(&tlsConfig); err != nil {
+					return fmt.Errorf("failed to configure TLS: %w", err)
+				}
+			}
+			if ctx.Bool("verbose") {
+				config.HttpClient.Transport = loggingTransport{next: config.HttpClient.Transport}
+			}
+			if ctx.Bool("stats") {
+				config.HttpClient.Transport = statsTransport{next: config.HttpClient.Transport}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if namespace := ctx.String("namespace"); namespace != "" {
+		client.SetNamespace(namespace)
+	}
+	if ctx.IsSet("timeout") {
+		// the client derives a context with this timeout for every request
+		client.SetClientTimeout(ctx.Duration("timeout"))
+	}
+	// the default retry policy of the vault client already covers 429 and 5xx
+	client.SetMaxRetries(ctx.Int("max-retries"))
+	client.SetMinRetryWait(minRetryWait)
+	client.SetMaxRetryWait(maxRetryWait)
+	client.SetBackoff(jitteredExponentialBackoff)
+	return client, nil
+}
+
+// reconfigureClient creates a copy of client with a modified configuration.
+// The configuration of the http client can only be changed this way.
+func reconfigureClient(client *api.Client, configure func(config *api.Config) error) (*api.Client, error) {
+	config := client.CloneConfig()
+	if err := configure(config); err != nil {
+		return nil, err
+	}
+	reconfigured, err := api.NewClient(config)
+	if err != nil {
+		return nil, fmt.Errorf("while initializing Vault client: %w", err)
+	}
+	reconfigured.SetToken(client.Token())
+	return reconfigured, nil
+}
+
+// jitteredExponentialBackoff doubles the wait time for each attempt and
+// randomizes it to avoid retrying concurrent requests in lockstep.
+func jitteredExponentialBackoff(minWait, maxWait time.Duration, attempt int, _ *http.Response) time.Duration {
+	wait := maxWait
+	if attempt < 32 && minWait<<attempt < maxWait {
+		wait = minWait << attempt
+	}
+	return wait/2 + rand.N(wait/2+1) //nolint:gosec // no cryptographic randomness required
+}
+
+// listedPath is a path as printed by listall --format ndjson.
+type listedPath struct {
+	Mount string `json:"mount"`
+	Path  string `json:"path"`
+}
+
+func listall(ctx *cli.Context) (err error) {
+	if err := checkForbiddenMode(ctx); err != nil {
+		return err
+	}
+	format := ctx.String("format")
+	details := ctx.Bool("details")
+	if details {
+		if err := checkFormat(format, "text", "json", "ndjson"); err != nil {
+			return err
+		}
+	} else if err := checkFormat(format, "text", "tree", "ndjson"); err != nil {
+		return err
+	}
+	modified, err := newModifiedFilter(ctx.String("modified-since"), ctx.String("modified-before"))
+	if err != nil {
+		return err
+	}
+	sortBy := ctx.String("sort")
+	if sortBy != "path" && sortBy != "updated_time" {
+		return fmt.Errorf("unsupported sort order %q", sortBy)
+	}
+	needsMetadata := details || modified != nil || sortBy == "updated_time"
+	if needsMetadata && ctx.Int("kv-version") != 2 {
+		return errors.New("--details, --modified-since/--modified-before and --sort updated_time are only supported for kvv2 engines")
+	}
+	if modified != nil && ctx.Bool("count") {
+		return errors.New("--count cannot be combined with --modified-since/--modified-before")
+	}
+	client, err := createClient(ctx)
+	if err != nil {
+		return err
+	}
+	out, err := openOutput(ctx)
+	if err != nil {
+		return err
+	}
+	defer closeOutput(out, &err)
+	lister := newLister(ctx, client)
+	if ctx.IsSet("filter") || ctx.IsSet("regex") {
+		lister.filter, err = newPathFilter(ctx.String("filter"), ctx.String("regex"))
+		if err != nil {
+			return err
+		}
+	}
+	lister.include, err = newPathPatterns(ctx.StringSlice("include"))
+	if err != nil {
+		return err
+	}
+	lister.exclude, err = newPathPatterns(ctx.StringSlice("exclude"))
+	if err != nil {
+		return err
+	}
+	filtered := lister.filter != nil || len(lister.include) > 0 || len(lister.exclude) > 0 || modified != nil
+	lister.countOnly = ctx.Bool("count")
+	if ctx.Bool("progress") {
+		stop := reportProgress(lister.printProgress)
+		defer stop()
+	}
+	complete := false
+	if ctx.IsSet("checkpoint") {
+		lister.checkpoint, err = loadCheckpoint(ctx.String("checkpoint"))
+		if err != nil {
+			return err
+		}
+		stop := reportProgress(lister.checkpoint.save)
+		defer func() {
+			stop()
+			// the checkpoint is only needed to resume an incomplete listing
+			if complete {
+				if err := os.Remove(lister.checkpoint.file); err != nil {
+					slog.Warn("failed to remove checkpoint", "file", lister.checkpoint.file, "error", err)
+				}
+			}
+		}()
+	}
+	start := normalizeDir(ctx.String("prefix"))
+	mounts := []string{lister.mount}
+	if ctx.Bool("all-mounts") {
+		mounts, err = listKVv2Mounts(ctx.Context, client)
+		if err != nil {
+			return err
+		}
+	}
+	// the metadata is fetched once all paths are known
+	stream := (ctx.Bool("stream") || format == "ndjson") && !needsMetadata && format != "tree"
+	encoder := json.NewEncoder(out)
+	printed := 0
+	allDetails := make([]pathDetails, 0)
+	errs := make([]error, 0)
+	for _, mount := range mounts {
+		lister.mount = mount
+		prefix := ""
+		if ctx.Bool("all-mounts") {
+			prefix = mount + "/"
+		}
+		printPath := func(path string) {
+			if format == "ndjson" {
+				_ = encoder.Encode(listedPath{Mount: mount, Path: relativePath(path)})
+			} else {
+				fmt.Fprintln(out, prefix+relativePath(path))
+			}
+			printed++
+		}
+		result := make([]string, 0)
+		// forbidden mounts are skipped like forbidden directories
+		err := lister.forEachSecret(ctx.Context, start, func(path string) {
+			if stream {
+				printPath(path)
+				return
+			}
+			result = append(result, path)
+		})
+		if err != nil {
+			return err
+		}
+		// the concurrent traversal returns paths in completion order
+		sort.Strings(result)
+		if needsMetadata {
+			fetched, fetchErrs := fetchMountDetails(ctx.Context, client, mount, result)
+			errs = append(errs, fetchErrs...)
+			fetched = modified.filter(fetched)
+			if sortBy == "updated_time" {
+				sortByUpdatedTime(fetched)
+			}
+			if details {
+				allDetails = append(allDetails, fetched...)
+				continue
+			}
+			result = result[:0]
+			for _, d := range fetched {
+				result = append(result, "/"+d.Path)
+			}
+		}
+		if format == "tree" {
+			printMountTree(out, mount, result, ctx.Bool("all-mounts"))
+			printed += len(result)
+			continue
+		}
+		for _, path := range result {
+			printPath(path)
+		}
+	}
+	complete = true
+	if err := lister.reportForbidden(); err != nil {
+		return err
+	}
+	if lister.countOnly {
+		_, err = fmt.Fprintln(out, lister.secretsCounted.Load())
+		return err
+	}
+	if details {
+		if err := printDetails(out, format, allDetails, ctx.Bool("all-mounts")); err != nil {
+			return err
+		}
+		printed = len(allDetails)
+	}
+	if len(errs) > 0 {
+		return joinErrors(errs, printed)
+	}
+	if filtered && printed == 0 {
+		return errNoResults
+	}
+	return nil
+}
+
+// lister walks the directory structure of a kv engine.
+type lister struct {
+	client    *api.Client
+	sema      *semaphore.Weighted
+	mount     string
+	kvVersion int
+	// if positive, directories at this depth are returned as-is instead of being descended into
+	maxDepth int
+	// if set, only matching secrets are returned
+	filter *pathFilter
+	// if set, only secrets matching any of include are returned, secrets and
+	// directories matching any of exclude are skipped
+	include []*pathFilter
+	exclude []*pathFilter
+	// if set, matching secrets are only counted in secretsCounted instead of being returned
+	countOnly bool
+	// if set, unexpected list responses abort the listing instead of being skipped
+	strict bool
+	// if set, list responses are served from and recorded in the checkpoint
+	checkpoint *checkpoint
+	// how forbidden directories are handled, either "fail", "skip" or "report"
+	forbidden string
+	// the directories skipped in "report" mode
+	forbiddenMutex sync.Mutex
+	forbiddenPaths []listedPath
+	secretsCounted atomic.Int64
+	// progress counters
+	dirsVisited  atomic.Int64
+	secretsFound atomic.Int64
+}
+
+func newLister(ctx *cli.Context, client *api.Client) *lister {
+	return &lister{
+		client:    client,
+		sema:      semaphore.NewWeighted(concurrency),
+		mount:     ctx.String("mount"),
+		kvVersion: ctx.Int("kv-version"),
+		maxDepth:  ctx.Int("max-depth"),
+		strict:    ctx.Bool("strict"),
+		forbidden: ctx.String("forbidden"),
+	}
+}
+
+// checkForbiddenMode validates the --forbidden flag.
+func checkForbiddenMode(ctx *cli.Context) error {
+	switch mode := ctx.String("forbidden"); mode {
+	case "fail", "skip", "report":
+		return nil
+	default:
+		return fmt.Errorf("unsupported --forbidden mode %q", mode)
+	}
+}
+
+// reportForbidden prints the directories skipped in "report" mode as JSON to
+// stderr, so that they do not mix with the listing.
+func (l *lister) reportForbidden() error {
+	if l.forbidden != "report" {
+		return nil
+	}
+	l.forbiddenMutex.Lock()
+	defer l.forbiddenMutex.Unlock()
+	sort.Slice(l.forbiddenPaths, func(i, j int) bool {
+		a, b := l.forbiddenPaths[i], l.forbiddenPaths[j]
+		return a.Mount < b.Mount || (a.Mount == b.Mount && a.Path < b.Path)
+	})
+	skipped := l.forbiddenPaths
+	if skipped == nil {
+		skipped = []listedPath{}
+	}
+	return json.NewEncoder(os.Stderr).Encode(map[string]any{"forbidden": skipped})
+}
+
+// listSecretDirRecurse lists all secrets below path.
+func (l *lister) listSecretDirRecurse(ctx context.Context, path string) ([]string, error) {
+	result := make([]string, 0)
+	err := l.forEachSecret(ctx, path, func(secret string) {
+		result = append(result, secret)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// forEachSecret calls fn for each secret below path as soon as it is found,
+// so that the paths do not have to be held in memory. fn is called from a
+// single goroutine.
+func (l *lister) forEachSecret(ctx context.Context, path string, fn func(secret string)) error {
+	found := make(chan string, concurrency)
+	walkErr := make(chan error, 1)
+	go func() {
+		walkErr <- l.walk(ctx, path, found)
+		close(found)
+	}()
+	for secret := range found {
+		fn(secret)
+	}
+	return <-walkErr
+}
+
+// walk sends all secrets below path to found and returns the first error
+// once all directories have been visited.
+func (l *lister) walk(ctx context.Context, path string, found chan<- string) error {
+	subPaths, err := l.listSecretDir(ctx, path)
+	if err != nil {
+		return err
+	}
+
+	var firstErr error
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+
+	for _, subPath := range subPaths {
+		next := path + subPath
+		if !strings.HasSuffix(next, "/") {
+			l.secretsFound.Add(1)
+			if !l.selects(relativePath(next)) {
+				continue
+			}
+		} else if !l.mayDescend(relativePath(next)) {
+			continue
+		}
+		if l.countOnly && !strings.HasSuffix(next, "/") {
+			l.secretsCounted.Add(1)
+			continue
+		}
+		if !strings.HasSuffix(next, "/") || (l.maxDepth > 0 && pathDepth(next) >= l.maxDepth) {
+			found <- next
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := l.walk(ctx, next, found); err != nil {
+				mutex.Lock()
+				defer mutex.Unlock()
+				if firstErr == nil {
+					firstErr = err
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return firstErr
+}
+
+// selects reports whether the secret at the given path passes all filters.
+func (l *lister) selects(secret string) bool {
+	if l.filter != nil && !l.filter.matches(secret) {
+		return false
+	}
+	if len(l.include) > 0 && !matchesAny(l.include, secret) {
+		return false
+	}
+	return !matchesAny(l.exclude, secret)
+}
+
+// mayDescend reports whether any secret below the given directory can pass
+// all filters.
+func (l *lister) mayDescend(dir string) bool {
+	if l.filter != nil && !l.filter.mayMatchBelow(dir) {
+		return false
+	}
+	if matchesAny(l.exclude, strings.TrimSuffix(dir, "/")) {
+		return false
+	}
+	if len(l.include) == 0 {
+		return true
+	}
+	for _, f := range l.include {
+		if f.mayMatchBelow(dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// reportProgress calls report periodically and a final time once the returned
+// function is called.
+func reportProgress(report func()) (stop func()) {
+	ticker := time.NewTicker(progressInterval)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				report()
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		ticker.Stop()
+		close(done)
+		report()
+	}
+}
+
+func (l *lister) printProgress() {
+	fmt.Fprintf(os.Stderr, "visited %d directories, found %d secrets\n", l.dirsVisited.Load(), l.secretsFound.Load())
+}
+
+// pathDepth returns the number of segments in path, e.g. 2 for "/a/b/".
+func pathDepth(path string) int {
+	trimmed := strings.Trim(path, "/")
+	if trimmed == "" {
+		return 0
+	}
+	return strings.Count(trimmed, "/") + 1
+}
+
+func (l *lister) listSecretDir(ctx context.Context, path string) ([]string, error) {
+	if l.checkpoint != nil {
+		if keys, ok := l.checkpoint.lookup(l.mount, path); ok {
+			l.dirsVisited.Add(1)
+			return keys, nil
+		}
+	}
+	if err := l.sema.Acquire(ctx, 1); err != nil {
+		return nil, err
+	}
+	// kvv1 engines have no metadata endpoint and are listed directly
+	listPath := fmt.Sprintf("%s/metadata/%s", l.mount, relativePath(path))
+	if l.kvVersion == 1 {
+		listPath = fmt.Sprintf("%s/%s", l.mount, relativePath(path))
+	}
+	data, err := l.client.Logical().ListWithContext(ctx, listPath)
+	l.sema.Release(1)
+	l.dirsVisited.Add(1)
+	var respError *api.ResponseError
+	if errors.As(err, &respError) && respError.StatusCode == http.StatusForbidden {
+		switch l.forbidden {
+		case "fail":
+			return nil, fmt.Errorf("access to %s in %s is forbidden", path, l.mount)
+		case "report":
+			l.forbiddenMutex.Lock()
+			l.forbiddenPaths = append(l.forbiddenPaths, listedPath{Mount: l.mount, Path: relativePath(path)})
+			l.forbiddenMutex.Unlock()
+		default:
+			slog.Warn("access is forbidden", "mount", l.mount, "path", path)
+		}
+		stats.forbiddenSkipped.Add(1)
+		return []string{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list keys in %s: %w", path, err)
+	}
+	// at a leaf secret
+	if data == nil {
+		if l.checkpoint != nil {
+			l.checkpoint.record(l.mount, path, []string{})
+		}
+		return []string{}, nil
+	}
+	interfaces, ok := data.Data["keys"].([]interface{})
+	if !ok {
+		err = fmt.Errorf("secret metadata at %s did not contain the expected keys", path)
+	} else {
+		var keys []string
+		keys, err = interfaceSliceToStringSlice(interfaces)
+		if err == nil {
+			if l.checkpoint != nil {
+				l.checkpoint.record(l.mount, path, keys)
+			}
+			return keys, nil
+		}
+		err = fmt.Errorf("retrieved secret keys at %s that are not strings: %w", path, err)
+	}
+	// a single odd response should not abort a large listing
+	if l.strict {
+		return nil, err
+	}
+	slog.Warn("skipping directory with unexpected list response", "mount", l.mount, "path", path, "error", err)
+	return []string{}, nil
+}
+
+func interfaceSliceToStringSlice(s []interface{}) ([]string, error) {
+	result := make([]string, 0)
+	for _, val := range s {
+		str, ok := val.(string)
+		if !ok {
+			return nil, errors.New("element is not a string")
+		}
+		result = append(result, str)
+	}
+	return result, nil
+}
+
+// readPaths returns the paths given as arguments or, if --stdin is set or the
+// only argument is "-", the non-empty lines read from stdin.
+func readPaths(ctx *cli.Context) ([]string, error) {
+	paths := make([]string, 0)
+	err := forEachPath(ctx, func(path string) error {
+		paths = append(paths, path)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return paths, nil
+}
+
+// forEachPath calls fn for each path selected like in readPaths. Paths read
+// from stdin are passed on as soon as their line is complete.
+func forEachPath(ctx *cli.Context, fn func(path string) error) error {
+	args := pathArgs(ctx)
+	if !ctx.Bool("stdin") && (len(args) != 1 || args[0] != "-") {
+		for _, path := range args {
+			if err := fn(path); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		path := cleanPath(strings.TrimSpace(scanner.Text()))
+		if path == "" {
+			continue
+		}
+		if err := fn(path); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read paths from stdin: %w", err)
+	}
+	return nil
+}
+
+// noMountCommands are the kv subcommands which do not operate on a mount.
+var noMountCommands = map[string]bool{"mounts": true, "validatemetas": true}
+
+// allMountsCommands are the kv subcommands supporting --all-mounts.
+var allMountsCommands = map[string]bool{"listall": true, "getcustommetas": true}
+
+// validateKVFlags checks the flags shared by all kv subcommands. It runs before
+// the subcommand is parsed, so the subcommand name is the first argument.
+func validateKVFlags(ctx *cli.Context) error {
+	if kvVersion := ctx.Int("kv-version"); kvVersion != 1 && kvVersion != 2 {
+		return fmt.Errorf("unsupported kv version %d", kvVersion)
+	}
+	if noMountCommands[ctx.Args().First()] {
+		return nil
+	}
+	if !ctx.Bool("all-mounts") {
+		if ctx.String("mount") == "" {
+			return fmt.Errorf("either --mount, the %s environment variable or --all-mounts is required", mountEnvVar)
+		}
+		// all subcommands read the normalized mount from the flag
+		return normalizeMountFlag("mount")(ctx, ctx.String("mount"))
+	}
+	// a mount from the environment is only a default and does not conflict
+	if ctx.IsSet("mount") && ctx.String("mount") != os.Getenv(mountEnvVar) {
+		return errors.New("--mount and --all-mounts are mutually exclusive")
+	}
+	if command := ctx.Args().First(); !allMountsCommands[command] {
+		return fmt.Errorf("%s does not support --all-mounts", command)
+	}
+	return nil
+}
+
+// listKVv2Mounts returns the paths of all kvv2 engines without trailing slash.
+func listKVv2Mounts(ctx context.Context, client *api.Client) ([]string, error) {
+	mounts, err := client.Sys().ListMountsWithContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list mounts: %w", err)
 	}
+	result := make([]string, 0)
+	for path, mount := range mounts {
+		if mount.Type == "kv" && mount.Options["version"] == "2" {
+			result = append(result, strings.TrimSuffix(path, "/"))
+		}
+	}
+	sort.Strings(result)
+	return result, nil
 }
 
-func listall(ctx *cli.Context) error {
-	client, err := vault.CreateClient()
+func listMounts(ctx *cli.Context) (err error) {
+	client, err := createClient(ctx)
 	if err != nil {
 		return err
 	}
-	sema := semaphore.NewWeighted(concurrency)
-	result, err := listSecretDirRecurse(ctx.Context, sema, client, ctx.String("mount"), "/")
+	out, err := openOutput(ctx)
+	if err != nil {
+		return err
+	}
+	defer closeOutput(out, &err)
+	mounts, err := listKVv2Mounts(ctx.Context, client)
 	if err != nil {
 		return err
 	}
-	for _, path := range result {
-		fmt.Println(path[1:])
+	for _, mount := range mounts {
+		fmt.Fprintln(out, mount)
 	}
 	return nil
 }
 
-func listSecretDirRecurse(ctx context.Context, sema *semaphore.Weighted, client *api.Client, mount, path string) ([]string, error) {
-	subPaths, err := listSecretDir(ctx, sema, client, mount, path)
-	if err != nil {
-		return nil, err
+// requireKVv2 rejects commands that rely on features only kvv2 engines provide.
+func requireKVv2(ctx *cli.Context) error {
+	if ctx.Int("kv-version") != 2 {
+		return fmt.Errorf("%s is only supported for kvv2 engines", ctx.Command.Name)
 	}
+	return nil
+}
 
-	result := make([]Result[[]string], 0)
-	var mutex sync.Mutex
-	var wg sync.WaitGroup
+// mapConcurrently calls fn for each input with a bounded number of calls in
+// flight and returns the results in the order of inputs.
+func mapConcurrently[In, Out any](ctx context.Context, inputs []In, fn func(input In) (Out, error)) []Result[Out] {
+	return mapConcurrentlyN(ctx, concurrency, inputs, fn)
+}
 
-	for _, subPath := range subPaths {
-		next := path + subPath
-		if !strings.HasSuffix(next, "/") {
-			result = append(result, Result[[]string]{value: []string{next}})
-			continue
-		}
+// mapConcurrentlyN is mapConcurrently with at most limit calls in flight.
+func mapConcurrentlyN[In, Out any](ctx context.Context, limit int64, inputs []In, fn func(input In) (Out, error)) []Result[Out] {
+	result := make([]Result[Out], len(inputs))
+	sema := semaphore.NewWeighted(limit)
+	var wg sync.WaitGroup
+	for idx, input := range inputs {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			subSecrets, err := listSecretDirRecurse(ctx, sema, client, mount, next)
-			mutex.Lock()
-			defer mutex.Unlock()
-			if err != nil {
-				result = append(result, Result[[]string]{value: nil, err: err})
+			if err := sema.Acquire(ctx, 1); err != nil {
+				result[idx] = Result[Out]{err: err}
 				return
 			}
-			result = append(result, Result[[]string]{value: subSecrets})
+			defer sema.Release(1)
+			value, err := fn(input)
+			result[idx] = Result[Out]{value: value, err: err}
 		}()
 	}
 	wg.Wait()
-	resultPaths := make([]string, 0)
-	for _, r := range result {
-		if r.err != nil {
-			return nil, r.err
+	return result
+}
+
+// listRecursive returns the paths of all secrets below the given directories.
+func listRecursive(ctx *cli.Context, client *api.Client, dirs []string) ([]string, error) {
+	lister := newLister(ctx, client)
+	result := make([]string, 0)
+	for _, dir := range dirs {
+		paths, err := lister.listSecretDirRecurse(ctx.Context, normalizeDir(dir))
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range paths {
+			result = append(result, relativePath(path))
 		}
-		resultPaths = append(resultPaths, r.value...)
 	}
-	return resultPaths, nil
+	return result, nil
 }
 
-func listSecretDir(ctx context.Context, sema *semaphore.Weighted, client *api.Client, mount, path string) ([]string, error) {
-	if err := sema.Acquire(ctx, 1); err != nil {
-		return nil, err
-	}
-	data, err := client.Logical().ListWithContext(ctx, fmt.Sprintf("%s/metadata/%s", mount, path))
-	sema.Release(1)
-	var respError *api.ResponseError
-	if errors.As(err, &respError) && respError.StatusCode == http.StatusForbidden {
-		fmt.Fprintf(os.Stderr, "access to %s is forbidden\n", path)
-		return []string{}, nil
+// fetchedCustomMeta is the custom metadata of a secret with the fields it can
+// be sorted by.
+type fetchedCustomMeta struct {
+	path           string
+	updatedTime    time.Time
+	customMetadata map[string]any
+}
+
+func getcustommetas(ctx *cli.Context) (err error) {
+	client, err := createClient(ctx)
+	if err != nil {
+		return err
 	}
+	out, err := openOutput(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list keys in %s: %w", path, err)
+		return err
 	}
-	// at a leaf secret
-	if data == nil {
-		return []string{}, nil
+	defer closeOutput(out, &err)
+	pathKey := ctx.String("path-key")
+	updatedTimeKey := ctx.String("updated-time-key")
+	if updatedTimeKey != "" && updatedTimeKey == pathKey {
+		return errors.New("--updated-time-key must differ from --path-key")
 	}
-	interfaces, ok := data.Data["keys"].([]interface{})
-	if !ok {
-		return nil, fmt.Errorf("secret metadata at %s did not contain the expected keys", path)
+	ndjson := ctx.Bool("ndjson")
+	format := ctx.String("format")
+	if err := checkFormat(format, "json", "yaml", "csv"); err != nil {
+		return err
 	}
-	keys, err := interfaceSliceToStringSlice(interfaces)
-	if err != nil {
-		return nil, fmt.Errorf("retrieved secret keys that are not strings: %w", err)
+	if ndjson && format != "json" {
+		return errors.New("--ndjson can only be used with --format json")
 	}
-	return keys, nil
-}
-
-func interfaceSliceToStringSlice(s []interface{}) ([]string, error) {
-	result := make([]string, 0)
-	for _, val := range s {
-		str, ok := val.(string)
-		if !ok {
-			return nil, errors.New("element is not a string")
-		}
-		result = append(result, str)
+	sortBy := ctx.String("sort")
+	if ndjson && !ctx.IsSet("sort") {
+		// --ndjson is meant for streaming, sorting would buffer everything
+		sortBy = "none"
 	}
-	return result, nil
-}
-
-func getcustommetas(ctx *cli.Context) error {
-	client, err := vault.CreateClient()
-	if err != nil {
-		return err
+	if sortBy != "path" && sortBy != "updated_time" && sortBy != "none" {
+		return fmt.Errorf("unsupported sort order %q", sortBy)
+	}
+	// with --all-mounts the paths are prefixed with their mount
+	var mounts []string
+	if ctx.Bool("all-mounts") {
+		mounts, err = listKVv2Mounts(ctx.Context, client)
+		if err != nil {
+			return err
+		}
 	}
-	result := make([]Result[map[string]any], 0)
+	encoder := json.NewEncoder(out)
+	result := make([]Result[fetchedCustomMeta], 0)
+	streamed := 0
 	var mutex sync.Mutex
 	var wg sync.WaitGroup
 	sema := semaphore.NewWeighted(concurrency)
 
-	for _, path := range ctx.Args().Slice() {
+	// paths from stdin are fetched as they arrive, acquiring the semaphore
+	// before reading on bounds the number of goroutines
+	fetch := func(path string) error {
+		if err := sema.Acquire(ctx.Context, 1); err != nil {
+			return err
+		}
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			if err := sema.Acquire(ctx.Context, 1); err != nil {
-				result = append(result, Result[map[string]any]{err: err})
-				return
+			mount, secretPath := ctx.String("mount"), path
+			var meta *api.KVMetadata
+			var err error
+			if mounts != nil {
+				var ok bool
+				mount, secretPath, ok = splitMountPath(mounts, path)
+				if !ok {
+					err = fmt.Errorf("%s is not below any kvv2 mount", path)
+				}
+			}
+			if err == nil {
+				meta, err = client.KVv2(mount).GetMetadata(ctx.Context, secretPath)
 			}
-			meta, err := client.KVv2(ctx.String("mount")).GetMetadata(ctx.Context, path)
 			sema.Release(1)
 			mutex.Lock()
 			defer mutex.Unlock()
 			if err != nil {
-				result = append(result, Result[map[string]any]{err: err})
+				result = append(result, Result[fetchedCustomMeta]{err: fmt.Errorf("failed to get metadata for %s: %w", path, err)})
 				return
 			}
 			if meta.CustomMetadata == nil {
 				meta.CustomMetadata = make(map[string]any)
 			}
-			meta.CustomMetadata["path"] = path
-			result = append(result, Result[map[string]any]{value: meta.CustomMetadata})
+			if _, exists := meta.CustomMetadata[pathKey]; exists {
+				result = append(result, Result[fetchedCustomMeta]{err: fmt.Errorf("custom metadata of %s already contains the key %q, choose another --path-key", path, pathKey)})
+				return
+			}
+			meta.CustomMetadata[pathKey] = path
+			if updatedTimeKey != "" {
+				if _, exists := meta.CustomMetadata[updatedTimeKey]; exists {
+					result = append(result, Result[fetchedCustomMeta]{err: fmt.Errorf("custom metadata of %s already contains the key %q, choose another --updated-time-key", path, updatedTimeKey)})
+					return
+				}
+				meta.CustomMetadata[updatedTimeKey] = meta.UpdatedTime.Format(time.RFC3339Nano)
+			}
+			if ndjson && sortBy == "none" {
+				// stream the object instead of collecting it
+				if err := encoder.Encode(meta.CustomMetadata); err != nil {
+					result = append(result, Result[fetchedCustomMeta]{err: fmt.Errorf("failed to write custom metadata of %s: %w", path, err)})
+					return
+				}
+				streamed++
+				return
+			}
+			result = append(result, Result[fetchedCustomMeta]{value: fetchedCustomMeta{path, meta.UpdatedTime, meta.CustomMetadata}})
 		}()
+		return nil
 	}
+	readErr := forEachPath(ctx, func(path string) error {
+		if !isGlob(path) {
+			return fetch(path)
+		}
+		if mounts != nil {
+			return fmt.Errorf("glob %s cannot be expanded with --all-mounts", path)
+		}
+		expanded, err := expandGlob(ctx, client, path)
+		if err != nil {
+			return err
+		}
+		for _, secretPath := range expanded {
+			if err := fetch(secretPath); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 
 	wg.Wait()
-	customMetas := make([]map[string]any, 0)
+	if readErr != nil {
+		return readErr
+	}
+	fetched := make([]fetchedCustomMeta, 0, len(result))
+	errs := make([]error, 0)
 	for _, r := range result {
 		if r.err != nil {
-			return r.err
+			errs = append(errs, r.err)
+			continue
+		}
+		fetched = append(fetched, r.value)
+	}
+	if err := printCustomMetas(out, format, pathKey, sortBy, ndjson, fetched); err != nil {
+		return err
+	}
+	return joinErrors(errs, streamed+len(fetched))
+}
+
+// printCustomMetas writes the fetched custom metadata sorted by sortBy.
+func printCustomMetas(out io.Writer, format, pathKey, sortBy string, ndjson bool, fetched []fetchedCustomMeta) error {
+	// the goroutines finish in a random order
+	switch sortBy {
+	case "path":
+		sort.Slice(fetched, func(i, j int) bool { return fetched[i].path < fetched[j].path })
+	case "updated_time":
+		sort.Slice(fetched, func(i, j int) bool { return fetched[i].updatedTime.Before(fetched[j].updatedTime) })
+	}
+	customMetas := make([]map[string]any, 0, len(fetched))
+	for _, f := range fetched {
+		customMetas = append(customMetas, f.customMetadata)
+	}
+	if ndjson {
+		encoder := json.NewEncoder(out)
+		for _, customMeta := range customMetas {
+			if err := encoder.Encode(customMeta); err != nil {
+				return err
+			}
 		}
-		customMetas = append(customMetas, r.value)
+		return nil
 	}
-	return json.NewEncoder(os.Stdout).Encode(customMetas)
+	if format == "csv" {
+		return writeCustomMetasCSV(out, pathKey, customMetas)
+	}
+	return encodeOutput(out, format, customMetas)
 }
 
 func setcustommetas(ctx *cli.Context) error {
-	client, err := vault.CreateClient()
+	client, err := createClient(ctx)
+	if err != nil {
+		return err
+	}
+	schema, err := loadSchemaFlag(ctx)
+	if err != nil {
+		return err
+	}
+	entries, err := readCustomMetaEntries(ctx, client)
+	if err != nil || len(entries) == 0 {
+		return err
+	}
+	update := customMetaUpdate{
+		// templates backfill keys, so they never replace the other keys
+		merge:      ctx.Bool("merge") || ctx.IsSet("template"),
+		deleteKeys: ctx.StringSlice("delete-key"),
+		dryRun:     ctx.Bool("dry-run"),
+		force:      ctx.Bool("force"),
+	}
+	if schema != nil {
+		if update.merge {
+			// the result depends on the current custom metadata of each secret
+			update.schema = schema
+		} else if err := validateAgainstSchema(schema, entries, update); err != nil {
+			return err
+		}
+	}
+	diffFormat := ctx.String("diff-format")
+	if err := checkFormat(diffFormat, "json", "text"); err != nil {
+		return err
+	}
+	if ctx.Int("concurrency") < 1 {
+		return errors.New("--concurrency must be at least 1")
+	}
+	failFast := ctx.Bool("fail-fast")
+	existenceCheck := !ctx.Bool("no-existence-check")
+	if !existenceCheck && (ctx.Bool("report") || update.dryRun || ctx.IsSet("updated-time-key") || update.schema != nil) {
+		return errors.New("--report, --dry-run, --updated-time-key and --schema with --merge need the previous metadata and cannot be combined with --no-existence-check")
+	}
+	// only replacing drops keys, merging keeps everything not mentioned
+	if !update.dryRun && !update.merge {
+		if err := confirm(ctx, "replace the custom metadata of", len(entries)); err != nil {
+			return err
+		}
+	}
+	writeCtx, cancel := context.WithCancel(ctx.Context)
+	defer cancel()
+	result := mapConcurrentlyN(writeCtx, int64(ctx.Int("concurrency")), entries, func(entry customMetaEntry) (metadataDiff, error) {
+		var diff metadataDiff
+		var err error
+		if existenceCheck {
+			diff, err = setCustomMeta(writeCtx, client, ctx.String("mount"), entry, update)
+		} else {
+			err = writeCustomMeta(writeCtx, client, ctx.String("mount"), entry, update)
+		}
+		if err != nil && failFast {
+			// do not start further writes
+			cancel()
+		}
+		return diff, err
+	})
+
+	errs := make([]error, 0)
+	reports := make([]customMetaReport, 0, len(result))
+	for idx, r := range result {
+		if r.err == nil {
+			reports = append(reports, customMetaReport{Path: entries[idx].path, metadataDiff: r.value})
+			continue
+		}
+		// writes aborted by cancellation are not reported individually
+		if errors.Is(r.err, context.Canceled) {
+			continue
+		}
+		if failFast {
+			errs = append(errs, r.err)
+			break
+		}
+		errs = append(errs, r.err)
+	}
+	if err := ctx.Context.Err(); err != nil {
+		errs = append(errs, err)
+	}
+	if ctx.Bool("report") || update.dryRun {
+		// also report the successful writes of a partially failed batch
+		if err := printCustomMetaReports(os.Stdout, diffFormat, reports); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return joinErrors(errs, len(reports))
+}
+
+// customMetaReport lists the custom metadata keys changed by setcustommetas for a path.
+type customMetaReport struct {
+	Path string `json:"path"`
+	metadataDiff
+}
+
+// printCustomMetaReports prints the reports as a JSON array or as "text" with
+// one changed key per line below each path.
+func printCustomMetaReports(w io.Writer, format string, reports []customMetaReport) error {
+	if format == "json" {
+		return json.NewEncoder(w).Encode(reports)
+	}
+	for _, report := range reports {
+		if report.isEmpty() {
+			fmt.Fprintf(w, "%s: no changes\n", report.Path)
+			continue
+		}
+		fmt.Fprintf(w, "%s:\n", report.Path)
+		report.write(w)
+	}
+	return nil
+}
+
+// customMetaEntry is a validated object of the setcustommetas input.
+type customMetaEntry struct {
+	path       string
+	customMeta map[string]any
+	// settings which are nil keep their current value
+	settings api.KVMetadataPatchInput
+	// if set, the metadata must not have been updated since then
+	updatedTime *time.Time
+}
+
+// readCustomMetaEntries decodes and validates the objects given to
+// setcustommetas or validatemetas on stdin, or renders them with --template.
+// An empty batch is an error with --strict and returns no entries otherwise.
+func readCustomMetaEntries(ctx *cli.Context, client *api.Client) ([]customMetaEntry, error) {
+	format := ctx.String("format")
+	if err := checkFormat(format, "json", "yaml", "csv"); err != nil {
+		return nil, err
+	}
+	if ctx.IsSet("template") {
+		entries, err := templateCustomMetaEntries(ctx, client)
+		if err != nil {
+			return nil, err
+		}
+		if len(entries) == 0 {
+			if ctx.Bool("strict") {
+				return nil, errors.New("no secrets match --match")
+			}
+			slog.Warn("no secrets match --match, nothing to update")
+			return nil, nil
+		}
+		return entries, nil
+	}
+	// decode the elements loosely so that malformed ones can be reported by index
+	customMetas := make([]any, 0)
+	var err error
+	switch format {
+	case "yaml":
+		err = yaml.NewDecoder(os.Stdin).Decode(&customMetas)
+	case "csv":
+		customMetas, err = readCustomMetasCSV(os.Stdin)
+	default:
+		err = json.NewDecoder(os.Stdin).Decode(&customMetas)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode stdin, expected an array of objects: %w", err)
+	}
+	if len(customMetas) == 0 {
+		if ctx.Bool("strict") {
+			return nil, errors.New("no objects were given on stdin")
+		}
+		slog.Warn("no objects were given on stdin, nothing to update")
+		return nil, nil
+	}
+	// validate the whole batch so that a malformed entry does not leave it half-applied
+	entries, err := validateCustomMetas(customMetas, ctx.String("path-key"), ctx.Bool("last-wins"))
+	if err != nil {
+		return nil, err
+	}
+	if key := ctx.String("updated-time-key"); key != "" {
+		if err := extractUpdatedTimes(entries, key); err != nil {
+			return nil, err
+		}
+	}
+	return entries, nil
+}
+
+// loadSchemaFlag loads the schema given with --schema, if any.
+func loadSchemaFlag(ctx *cli.Context) (*jsonSchema, error) {
+	if !ctx.IsSet("schema") {
+		return nil, nil
+	}
+	return loadJSONSchema(ctx.String("schema"))
+}
+
+func validatemetas(ctx *cli.Context) error {
+	schema, err := loadSchemaFlag(ctx)
 	if err != nil {
 		return err
 	}
-	customMetas := make([]map[string]any, 0)
-	if err = json.NewDecoder(os.Stdin).Decode(&customMetas); err != nil {
+	entries, err := readCustomMetaEntries(ctx, nil)
+	if err != nil {
 		return err
 	}
-	for _, customMeta := range customMetas {
-		pathInterface, ok := customMeta["path"]
+	// the objects are validated as they would replace the custom metadata
+	if schema != nil {
+		if err := validateAgainstSchema(schema, entries, customMetaUpdate{}); err != nil {
+			return err
+		}
+	}
+	fmt.Printf("%d object(s) are valid\n", len(entries))
+	return nil
+}
+
+// validateCustomMetas checks every object of the input and reports all invalid
+// objects at once. Unless lastWins is set, duplicate paths are rejected,
+// otherwise only the last object for each path is kept.
+func validateCustomMetas(customMetas []any, pathKey string, lastWins bool) ([]customMetaEntry, error) {
+	entries := make([]customMetaEntry, 0, len(customMetas))
+	indexByPath := make(map[string]int)
+	errs := make([]error, 0)
+	for idx, element := range customMetas {
+		customMeta, ok := element.(map[string]any)
+		if !ok {
+			errs = append(errs, fmt.Errorf("object %d is %s instead of an object", idx, describeValue(element)))
+			continue
+		}
+		pathInterface, ok := customMeta[pathKey]
 		if !ok {
-			return errors.New("found object without path key")
+			errs = append(errs, fmt.Errorf("object %d has no %s key", idx, pathKey))
+			continue
 		}
 		path, ok := pathInterface.(string)
 		if !ok {
-			return errors.New("found object with non-string value for path")
+			errs = append(errs, fmt.Errorf("object %d has a non-string value for %s", idx, pathKey))
+			continue
+		}
+		path = cleanPath(path)
+		if path == "" {
+			errs = append(errs, fmt.Errorf("object %d has an empty path", idx))
+			continue
 		}
-		delete(customMeta, "path")
-		meta, err := client.KVv2(ctx.String("mount")).GetMetadata(ctx.Context, path)
+		delete(customMeta, pathKey)
+		settings, err := extractMetadataSettings(customMeta)
 		if err != nil {
-			return err
+			errs = append(errs, fmt.Errorf("object %d has invalid metadata settings for %s: %w", idx, path, err))
+			continue
+		}
+		entry := customMetaEntry{path: path, customMeta: customMeta, settings: settings}
+		if previous, exists := indexByPath[path]; exists {
+			if !lastWins {
+				errs = append(errs, fmt.Errorf("object %d has the same path %s as an earlier object, pass --last-wins to allow this", idx, path))
+				continue
+			}
+			entries[previous] = entry
+			continue
+		}
+		indexByPath[path] = len(entries)
+		entries = append(entries, entry)
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return entries, nil
+}
+
+// extractUpdatedTimes removes the updated time emitted by getcustommetas under
+// key from the custom metadata of the entries and records it for the check
+// before writing.
+func extractUpdatedTimes(entries []customMetaEntry, key string) error {
+	errs := make([]error, 0)
+	for idx := range entries {
+		entry := &entries[idx]
+		value, ok := entry.customMeta[key]
+		if !ok {
+			errs = append(errs, fmt.Errorf("object for %s has no %s key", entry.path, key))
+			continue
 		}
-		if meta == nil {
-			return fmt.Errorf("secret on path %s does not exist", path)
+		delete(entry.customMeta, key)
+		str, ok := value.(string)
+		if !ok {
+			errs = append(errs, fmt.Errorf("object for %s has %s instead of a timestamp for %s", entry.path, describeValue(value), key))
+			continue
 		}
-		err = client.KVv2(ctx.String("mount")).PutMetadata(ctx.Context, path, api.KVMetadataPutInput{
-			CustomMetadata: customMeta,
-		})
+		updatedTime, err := time.Parse(time.RFC3339Nano, str)
 		if err != nil {
-			return err
+			errs = append(errs, fmt.Errorf("object for %s has an invalid %s: %w", entry.path, key, err))
+			continue
+		}
+		entry.updatedTime = &updatedTime
+	}
+	return errors.Join(errs...)
+}
+
+// describeValue names the type of a decoded JSON or YAML value for error messages.
+func describeValue(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "a string"
+	case bool:
+		return "a boolean"
+	case float64, int:
+		return "a number"
+	case []any:
+		return "an array"
+	default:
+		return fmt.Sprintf("a %T", value)
+	}
+}
+
+// customMetaUpdate selects how the custom metadata of an entry is combined
+// with the current custom metadata of the secret.
+type customMetaUpdate struct {
+	// if set, the given keys are added to the current ones instead of replacing them
+	merge bool
+	// keys removed from the resulting custom metadata
+	deleteKeys []string
+	// if set, the changes are only computed and not written
+	dryRun bool
+	// if set, the metadata is written even if it changed since getcustommetas read it
+	force bool
+	// if set, the resulting custom metadata of each secret must match it
+	schema *jsonSchema
+}
+
+// apply returns the custom metadata resulting from updating current with given.
+func (u customMetaUpdate) apply(current, given map[string]any) map[string]any {
+	result := make(map[string]any, len(current)+len(given))
+	if u.merge {
+		for key, value := range current {
+			result[key] = value
+		}
+	}
+	for key, value := range given {
+		result[key] = value
+	}
+	for _, key := range u.deleteKeys {
+		delete(result, key)
+	}
+	return result
+}
+
+// setCustomMeta updates the custom metadata of a secret and returns the
+// changes compared to the previous custom metadata.
+func setCustomMeta(ctx context.Context, client *api.Client, mount string, entry customMetaEntry, update customMetaUpdate) (metadataDiff, error) {
+	path := entry.path
+	meta, err := client.KVv2(mount).GetMetadata(ctx, path)
+	if err != nil {
+		return metadataDiff{}, fmt.Errorf("failed to get metadata for %s: %w", path, err)
+	}
+	if meta == nil {
+		return metadataDiff{}, fmt.Errorf("secret on path %s does not exist", path)
+	}
+	customMeta := update.apply(meta.CustomMetadata, entry.customMeta)
+	if update.schema != nil {
+		if problems := update.schema.validate(customMeta, ""); len(problems) > 0 {
+			return metadataDiff{}, fmt.Errorf("custom metadata of %s would not match the schema: %s", path, strings.Join(problems, ", "))
 		}
 	}
+	input := api.KVMetadataPutInput{
+		CASRequired:        meta.CASRequired,
+		CustomMetadata:     customMeta,
+		DeleteVersionAfter: meta.DeleteVersionAfter,
+		MaxVersions:        meta.MaxVersions,
+	}
+	if entry.settings.CASRequired != nil {
+		input.CASRequired = *entry.settings.CASRequired
+	}
+	if entry.settings.DeleteVersionAfter != nil {
+		input.DeleteVersionAfter = *entry.settings.DeleteVersionAfter
+	}
+	if entry.settings.MaxVersions != nil {
+		input.MaxVersions = *entry.settings.MaxVersions
+	}
+	// vault has no check-and-set for metadata, so the updated time which
+	// getcustommetas emitted tells whether someone else changed it since
+	if entry.updatedTime != nil && !update.force && !meta.UpdatedTime.Equal(*entry.updatedTime) {
+		return metadataDiff{}, fmt.Errorf("metadata of %s was changed at %s after it was read, retry or pass --force to overwrite it", path, meta.UpdatedTime.Format(time.RFC3339Nano))
+	}
+	if !update.dryRun {
+		err = client.KVv2(mount).PutMetadata(ctx, path, input)
+		if err != nil {
+			return metadataDiff{}, fmt.Errorf("failed to update metadata for %s: %w", path, err)
+		}
+	}
+	return diffMetadata(meta.CustomMetadata, customMeta), nil
+}
+
+// writeCustomMeta updates the custom metadata of a secret without reading its
+// metadata first. Unlike PutMetadata, only the settings given in entry are sent
+// so that Vault keeps the others.
+func writeCustomMeta(ctx context.Context, client *api.Client, mount string, entry customMetaEntry, update customMetaUpdate) error {
+	if update.merge {
+		// a JSON merge patch keeps the keys which are not mentioned
+		patch := entry.settings
+		patch.CustomMetadata = make(map[string]any, len(entry.customMeta)+len(update.deleteKeys))
+		for key, value := range entry.customMeta {
+			patch.CustomMetadata[key] = value
+		}
+		for _, key := range update.deleteKeys {
+			patch.CustomMetadata[key] = nil
+		}
+		if err := client.KVv2(mount).PatchMetadata(ctx, entry.path, patch); err != nil {
+			return fmt.Errorf("failed to update metadata for %s: %w", entry.path, err)
+		}
+		return nil
+	}
+	data := map[string]any{"custom_metadata": update.apply(nil, entry.customMeta)}
+	if entry.settings.CASRequired != nil {
+		data["cas_required"] = *entry.settings.CASRequired
+	}
+	if entry.settings.DeleteVersionAfter != nil {
+		data["delete_version_after"] = entry.settings.DeleteVersionAfter.String()
+	}
+	if entry.settings.MaxVersions != nil {
+		data["max_versions"] = *entry.settings.MaxVersions
+	}
+	_, err := client.Logical().WriteWithContext(ctx, fmt.Sprintf("%s/metadata/%s", mount, entry.path), data)
+	if err != nil {
+		return fmt.Errorf("failed to update metadata for %s: %w", entry.path, err)
+	}
 	return nil
 }
+
+// extractMetadataSettings removes the optional max_versions, cas_required and
+// delete_version_after keys from customMeta and returns their values.
+func extractMetadataSettings(customMeta map[string]any) (api.KVMetadataPatchInput, error) {
+	var settings api.KVMetadataPatchInput
+	if value, ok := customMeta["max_versions"]; ok {
+		// JSON numbers decode as float64, YAML integers as int
+		number, ok := value.(float64)
+		if i, isInt := value.(int); isInt {
+			number, ok = float64(i), true
+		}
+		if !ok || number < 0 || number != float64(int(number)) {
+			return settings, errors.New("max_versions must be a non-negative integer")
+		}
+		maxVersions := int(number)
+		settings.MaxVersions = &maxVersions
+		delete(customMeta, "max_versions")
+	}
+	if value, ok := customMeta["cas_required"]; ok {
+		casRequired, ok := value.(bool)
+		if !ok {
+			return settings, errors.New("cas_required must be a boolean")
+		}
+		settings.CASRequired = &casRequired
+		delete(customMeta, "cas_required")
+	}
+	if value, ok := customMeta["delete_version_after"]; ok {
+		str, ok := value.(string)
+		if !ok {
+			return settings, errors.New("delete_version_after must be a duration string")
+		}
+		duration, err := time.ParseDuration(str)
+		if err != nil {
+			return settings, fmt.Errorf("delete_version_after must be a duration string: %w", err)
+		}
+		settings.DeleteVersionAfter = &duration
+		delete(customMeta, "delete_version_after")
+	}
+	return settings, nil
+}
@@ -0,0 +1,113 @@
+/******************************************************************************
+*
+*  Copyright 2024 SAP SE
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/urfave/cli/v2"
+)
+
+func setmetaconfig(ctx *cli.Context) error {
+	var patch api.KVMetadataPatchInput
+	if ctx.IsSet("max-versions") {
+		maxVersions := ctx.Int("max-versions")
+		if maxVersions < 0 {
+			return errors.New("--max-versions must not be negative")
+		}
+		patch.MaxVersions = &maxVersions
+	}
+	if ctx.IsSet("delete-version-after") {
+		deleteVersionAfter := ctx.Duration("delete-version-after")
+		if deleteVersionAfter < 0 {
+			return errors.New("--delete-version-after must not be negative")
+		}
+		patch.DeleteVersionAfter = &deleteVersionAfter
+	}
+	if ctx.IsSet("cas-required") {
+		casRequired := ctx.Bool("cas-required")
+		patch.CASRequired = &casRequired
+	}
+	if patch.MaxVersions == nil && patch.DeleteVersionAfter == nil && patch.CASRequired == nil {
+		return errors.New("at least one of --max-versions, --delete-version-after and --cas-required is required")
+	}
+	client, err := createClient(ctx)
+	if err != nil {
+		return err
+	}
+	paths, err := readPathsExpanded(ctx, client)
+	if err != nil {
+		return err
+	}
+	dryRun := ctx.Bool("dry-run")
+	if !dryRun {
+		if err := confirm(ctx, "change the metadata settings of", len(paths)); err != nil {
+			return err
+		}
+	}
+	kv := client.KVv2(ctx.String("mount"))
+	result := mapConcurrently(ctx.Context, paths, func(path string) (string, error) {
+		meta, err := kv.GetMetadata(ctx.Context, path)
+		if err != nil {
+			return "", fmt.Errorf("failed to get metadata for %s: %w", path, err)
+		}
+		changes := describeSettingChanges(meta, patch)
+		if len(changes) == 0 || dryRun {
+			return changes, nil
+		}
+		if err := kv.PatchMetadata(ctx.Context, path, patch); err != nil {
+			return "", fmt.Errorf("failed to update metadata for %s: %w", path, err)
+		}
+		return changes, nil
+	})
+
+	errs := make([]error, 0)
+	for idx, r := range result {
+		switch {
+		case r.err != nil:
+			errs = append(errs, r.err)
+		case r.value == "":
+			fmt.Printf("%s: unchanged\n", paths[idx])
+		case dryRun:
+			fmt.Printf("%s: would change %s\n", paths[idx], r.value)
+		default:
+			fmt.Printf("%s: changed %s\n", paths[idx], r.value)
+		}
+	}
+	return joinErrors(errs, len(paths)-len(errs))
+}
+
+// describeSettingChanges lists the settings patch changes compared to meta,
+// e.g. "max_versions 0 -> 5", or returns "" if nothing changes.
+func describeSettingChanges(meta *api.KVMetadata, patch api.KVMetadataPatchInput) string {
+	changes := make([]string, 0, 3)
+	if patch.MaxVersions != nil && *patch.MaxVersions != meta.MaxVersions {
+		changes = append(changes, fmt.Sprintf("max_versions %d -> %d", meta.MaxVersions, *patch.MaxVersions))
+	}
+	if patch.DeleteVersionAfter != nil && *patch.DeleteVersionAfter != meta.DeleteVersionAfter {
+		changes = append(changes, fmt.Sprintf("delete_version_after %s -> %s", meta.DeleteVersionAfter, *patch.DeleteVersionAfter))
+	}
+	if patch.CASRequired != nil && *patch.CASRequired != meta.CASRequired {
+		changes = append(changes, fmt.Sprintf("cas_required %t -> %t", meta.CASRequired, *patch.CASRequired))
+	}
+	return strings.Join(changes, ", ")
+}
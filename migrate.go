@@ -0,0 +1,86 @@
+/******************************************************************************
+*
+*  Copyright 2024 SAP SE
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/urfave/cli/v2"
+)
+
+func migrateV1(ctx *cli.Context) error {
+	client, err := createClient(ctx)
+	if err != nil {
+		return err
+	}
+	// the source is always a kvv1 engine, regardless of --kv-version
+	lister := newLister(ctx, client)
+	lister.kvVersion = 1
+	paths, err := lister.listSecretDirRecurse(ctx.Context, "/")
+	if err != nil {
+		return err
+	}
+	sort.Strings(paths)
+
+	srcKV, dstKV := client.KVv1(ctx.String("mount")), client.KVv2(ctx.String("dst-mount"))
+	skipExisting := ctx.Bool("skip-existing")
+	stampKey := ctx.String("stamp")
+	stamp := time.Now().UTC().Format(time.RFC3339)
+	result := mapConcurrently(ctx.Context, paths, func(path string) (bool, error) {
+		path = relativePath(path)
+		if skipExisting {
+			exists, err := secretExists(ctx.Context, client, ctx.String("dst-mount"), path)
+			if err != nil || exists {
+				return false, err
+			}
+		}
+		secret, err := srcKV.Get(ctx.Context, path)
+		if err != nil {
+			return false, fmt.Errorf("failed to read secret %s: %w", path, err)
+		}
+		if _, err := dstKV.Put(ctx.Context, path, secret.Data); err != nil {
+			return false, fmt.Errorf("failed to write secret %s: %w", path, err)
+		}
+		if stampKey != "" {
+			patch := api.KVMetadataPatchInput{CustomMetadata: map[string]any{stampKey: stamp}}
+			if err := dstKV.PatchMetadata(ctx.Context, path, patch); err != nil {
+				return false, fmt.Errorf("failed to update metadata for %s: %w", path, err)
+			}
+		}
+		return true, nil
+	})
+
+	var migrated, skipped int
+	errs := make([]error, 0)
+	for _, r := range result {
+		switch {
+		case r.err != nil:
+			errs = append(errs, r.err)
+		case r.value:
+			migrated++
+		default:
+			skipped++
+		}
+	}
+	fmt.Printf("migrated: %d, skipped: %d, failed: %d\n", migrated, skipped, len(errs))
+	return joinErrors(errs, migrated+skipped)
+}
@@ -0,0 +1,183 @@
+/******************************************************************************
+*
+*  Copyright 2024 SAP SE
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/urfave/cli/v2"
+	"golang.org/x/net/websocket"
+)
+
+// kvEvent is the part of a kv-v2 event notification used by mirror.
+type kvEvent struct {
+	Data struct {
+		EventType string `json:"event_type"`
+		Event     struct {
+			Metadata struct {
+				Path string `json:"path"`
+			} `json:"metadata"`
+		} `json:"event"`
+		PluginInfo struct {
+			MountPath string `json:"mount_path"`
+		} `json:"plugin_info"`
+	} `json:"data"`
+}
+
+func mirror(ctx *cli.Context) error {
+	if !ctx.Bool("follow") {
+		return syncSecrets(ctx)
+	}
+	client, err := createClient(ctx)
+	if err != nil {
+		return err
+	}
+	dstClient, err := createDestinationClient(ctx, client)
+	if err != nil {
+		return err
+	}
+	dstMount := ctx.String("dst-mount")
+	if dstMount == "" {
+		dstMount = ctx.String("mount")
+	}
+	mount := ctx.String("mount")
+	dirs := make([]string, 0, ctx.NArg())
+	for _, dir := range pathArgs(ctx) {
+		dirs = append(dirs, normalizeDir(dir))
+	}
+	srcKV, dstKV := client.KVv2(mount), dstClient.KVv2(dstMount)
+	for {
+		err := followEvents(ctx, client, func(event kvEvent) {
+			if err := mirrorEvent(ctx.Context, srcKV, dstKV, mount, dirs, event); err != nil {
+				slog.Warn("failed to mirror event", "type", event.Data.EventType, "path", event.Data.Event.Metadata.Path, "error", err)
+			}
+		})
+		if ctx.Context.Err() != nil {
+			return ctx.Context.Err()
+		}
+		slog.Warn("event subscription ended, reconnecting", "error", err)
+		select {
+		case <-time.After(maxRetryWait):
+		case <-ctx.Context.Done():
+			return ctx.Context.Err()
+		}
+	}
+}
+
+// followEvents subscribes to all kv-v2 events, runs a full sync to catch up
+// with the changes missed while not subscribed and then calls handle for each
+// event until the subscription fails.
+func followEvents(ctx *cli.Context, client *api.Client, handle func(event kvEvent)) error {
+	addr := strings.Replace(client.Address(), "http", "ws", 1)
+	config, err := websocket.NewConfig(addr+"/v1/sys/events/subscribe/kv-v2/*?json=true", client.Address())
+	if err != nil {
+		return err
+	}
+	config.Header.Set("X-Vault-Token", client.Token())
+	if namespace := client.Namespace(); namespace != "" {
+		config.Header.Set("X-Vault-Namespace", namespace)
+	}
+	if transport, ok := client.CloneConfig().HttpClient.Transport.(*http.Transport); ok {
+		config.TlsConfig = transport.TLSClientConfig
+	}
+	conn, err := config.DialContext(ctx.Context)
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to events: %w", err)
+	}
+	defer conn.Close()
+	// unblock the receive below on cancellation
+	stop := context.AfterFunc(ctx.Context, func() { conn.Close() })
+	defer stop()
+
+	// a few unreadable secrets must not keep the events from being processed
+	var partial partialFailureError
+	if err := syncSecrets(ctx); errors.As(err, &partial) {
+		slog.Warn("failed to sync some secrets", "error", err)
+	} else if err != nil {
+		return err
+	}
+	for {
+		var event kvEvent
+		if err := websocket.JSON.Receive(conn, &event); err != nil {
+			return fmt.Errorf("failed to receive event: %w", err)
+		}
+		handle(event)
+	}
+}
+
+// isBelowAny reports whether path is below one of dirs, an empty list of
+// directories selects all paths.
+func isBelowAny(path string, dirs []string) bool {
+	if len(dirs) == 0 {
+		return true
+	}
+	for _, dir := range dirs {
+		if strings.HasPrefix(path, dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// mirrorEvent applies the change described by an event of the source mount
+// to the destination if it concerns a secret below one of dirs.
+func mirrorEvent(ctx context.Context, srcKV, dstKV *api.KVv2, mount string, dirs []string, event kvEvent) error {
+	if strings.TrimSuffix(event.Data.PluginInfo.MountPath, "/") != mount {
+		return nil
+	}
+	// the event path is e.g. "mount/data/path" or "mount/metadata/path"
+	_, path, ok := strings.Cut(strings.TrimPrefix(event.Data.Event.Metadata.Path, mount+"/"), "/")
+	if !ok || path == "" {
+		return errors.New("event has no secret path")
+	}
+	if !isBelowAny("/"+path, dirs) {
+		return nil
+	}
+	switch strings.TrimPrefix(event.Data.EventType, "kv-v2/") {
+	case "metadata-delete":
+		if err := dstKV.DeleteMetadata(ctx, path); err != nil {
+			return err
+		}
+		fmt.Printf("%s: deleted\n", path)
+	case "data-delete", "delete":
+		if err := dstKV.Delete(ctx, path); err != nil {
+			return err
+		}
+		fmt.Printf("%s: deleted latest version\n", path)
+	case "destroy":
+		// version numbers differ between source and destination
+		slog.Debug("ignoring destroyed versions", "path", path)
+	default:
+		change, err := syncSecret(ctx, srcKV, dstKV, path, false)
+		if err != nil {
+			return err
+		}
+		if change != "" {
+			fmt.Printf("%s: %s\n", path, change)
+		}
+	}
+	return nil
+}
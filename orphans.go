@@ -0,0 +1,119 @@
+/******************************************************************************
+*
+*  Copyright 2024 SAP SE
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/urfave/cli/v2"
+)
+
+// isDeleted reports whether a version was soft-deleted. With
+// delete_version_after, live versions carry a deletion time in the future.
+func isDeleted(v api.KVVersionMetadata) bool {
+	return !v.DeletionTime.IsZero() && v.DeletionTime.Before(time.Now())
+}
+
+// isOrphan reports whether no version of a secret can be read anymore.
+func isOrphan(meta *api.KVMetadata) bool {
+	for _, v := range meta.Versions {
+		if !v.Destroyed && !isDeleted(v) {
+			return false
+		}
+	}
+	return true
+}
+
+func orphans(ctx *cli.Context) (err error) {
+	client, err := createClient(ctx)
+	if err != nil {
+		return err
+	}
+	out, err := openOutput(ctx)
+	if err != nil {
+		return err
+	}
+	defer closeOutput(out, &err)
+	kv := client.KVv2(ctx.String("mount"))
+	lister := newLister(ctx, client)
+	paths, err := lister.listSecretDirRecurse(ctx.Context, "/")
+	if err != nil {
+		return err
+	}
+	sort.Strings(paths)
+
+	result := mapConcurrently(ctx.Context, paths, func(path string) (bool, error) {
+		meta, err := kv.GetMetadata(ctx.Context, relativePath(path))
+		if err != nil {
+			return false, fmt.Errorf("failed to get metadata for %s: %w", relativePath(path), err)
+		}
+		return isOrphan(meta), nil
+	})
+	found := make([]string, 0)
+	errs := make([]error, 0)
+	for idx, r := range result {
+		switch {
+		case r.err != nil:
+			errs = append(errs, r.err)
+		case r.value:
+			found = append(found, relativePath(paths[idx]))
+		}
+	}
+	for _, path := range found {
+		fmt.Fprintln(out, path)
+	}
+
+	if ctx.Bool("purge") && len(found) > 0 {
+		if err := confirm(ctx, "delete the metadata of", len(found)); err != nil {
+			return err
+		}
+		purged := mapConcurrently(ctx.Context, found, func(path string) (struct{}, error) {
+			// the secret might have been written since the scan, which would
+			// otherwise destroy its new versions for good
+			meta, err := kv.GetMetadata(ctx.Context, path)
+			if err != nil {
+				return struct{}{}, fmt.Errorf("failed to get metadata for %s: %w", path, err)
+			}
+			if !isOrphan(meta) {
+				slog.Warn("skipping secret, it was written since the scan", "path", path)
+				return struct{}{}, nil
+			}
+			if err := kv.DeleteMetadata(ctx.Context, path); err != nil {
+				return struct{}{}, fmt.Errorf("failed to delete metadata of %s: %w", path, err)
+			}
+			return struct{}{}, nil
+		})
+		for _, r := range purged {
+			if r.err != nil {
+				errs = append(errs, r.err)
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return joinErrors(errs, len(paths)-len(errs))
+	}
+	if len(found) == 0 {
+		return errNoResults
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+/******************************************************************************
+*
+*  Copyright 2024 SAP SE
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+)
+
+func TestIsOrphan(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+	cases := []struct {
+		name     string
+		versions map[string]api.KVVersionMetadata
+		expected bool
+	}{
+		{"no versions", map[string]api.KVVersionMetadata{}, true},
+		{"live", map[string]api.KVVersionMetadata{"1": {Version: 1}}, false},
+		{"deleted", map[string]api.KVVersionMetadata{"1": {Version: 1, DeletionTime: past}}, true},
+		{"destroyed", map[string]api.KVVersionMetadata{"1": {Version: 1, Destroyed: true}}, true},
+		{"deletion scheduled", map[string]api.KVVersionMetadata{"1": {Version: 1, DeletionTime: future}}, false},
+		{"older version live", map[string]api.KVVersionMetadata{
+			"1": {Version: 1},
+			"2": {Version: 2, DeletionTime: past},
+		}, false},
+		{"all gone", map[string]api.KVVersionMetadata{
+			"1": {Version: 1, Destroyed: true},
+			"2": {Version: 2, DeletionTime: past},
+		}, true},
+	}
+	for _, c := range cases {
+		if actual := isOrphan(&api.KVMetadata{Versions: c.versions}); actual != c.expected {
+			t.Errorf("%s: isOrphan() = %t, expected %t", c.name, actual, c.expected)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+/******************************************************************************
+*
+*  Copyright 2024 SAP SE
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/urfave/cli/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// outputFlags are the flags of all commands printing their results to stdout.
+func outputFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:    "output",
+			Aliases: []string{"o"},
+			Usage:   "File to write the results to instead of stdout",
+		},
+		&cli.BoolFlag{
+			Name:  "append",
+			Usage: "Append to the --output file instead of truncating it",
+		},
+	}
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
+
+// openOutput opens the file selected by --output or returns stdout.
+func openOutput(ctx *cli.Context) (io.WriteCloser, error) {
+	if !ctx.IsSet("output") {
+		return nopCloser{os.Stdout}, nil
+	}
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if ctx.Bool("append") {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	}
+	file, err := os.OpenFile(ctx.String("output"), flags, 0o666)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open output file: %w", err)
+	}
+	return file, nil
+}
+
+// closeOutput closes out and adds a failure to err, it is meant to be deferred.
+func closeOutput(out io.Closer, err *error) {
+	if closeErr := out.Close(); closeErr != nil {
+		*err = errors.Join(*err, fmt.Errorf("failed to write output file: %w", closeErr))
+	}
+}
+
+// checkFormat returns an error if format is not one of the given formats.
+func checkFormat(format string, supported ...string) error {
+	for _, s := range supported {
+		if format == s {
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported format %q", format)
+}
+
+// encodeOutput writes v to w as either "json" or "yaml".
+func encodeOutput(w io.Writer, format string, v any) error {
+	if format == "yaml" {
+		encoder := yaml.NewEncoder(w)
+		if err := encoder.Encode(v); err != nil {
+			return err
+		}
+		return encoder.Close()
+	}
+	return json.NewEncoder(w).Encode(v)
+}
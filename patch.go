@@ -0,0 +1,75 @@
+/******************************************************************************
+*
+*  Copyright 2024 SAP SE
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/urfave/cli/v2"
+)
+
+func patch(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return errors.New("expected exactly one path argument")
+	}
+	path := cleanPath(ctx.Args().First())
+	client, err := createClient(ctx)
+	if err != nil {
+		return err
+	}
+	data := make(map[string]any)
+	if err = json.NewDecoder(os.Stdin).Decode(&data); err != nil {
+		return fmt.Errorf("failed to decode stdin, expected a JSON object: %w", err)
+	}
+	// only use the server-side merge patch, never a client-side read-then-write
+	opts := []api.KVOption{api.WithMergeMethod(api.KVMergeMethodPatch)}
+	if ctx.IsSet("cas") {
+		opts = append(opts, api.WithCheckAndSet(ctx.Int("cas")))
+	}
+	written, err := client.KVv2(ctx.String("mount")).Patch(ctx.Context, path, data, opts...)
+	if isCASMismatch(err) {
+		return fmt.Errorf("secret %s was not patched because its current version is not %d", path, ctx.Int("cas"))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to patch secret %s: %w", path, err)
+	}
+	fmt.Printf("%s: version %d\n", path, written.VersionMetadata.Version)
+	return nil
+}
+
+// isCASMismatch reports whether err is Vault rejecting a write because of a
+// check-and-set version mismatch.
+func isCASMismatch(err error) bool {
+	var respError *api.ResponseError
+	if !errors.As(err, &respError) || respError.StatusCode != http.StatusBadRequest {
+		return false
+	}
+	for _, message := range respError.Errors {
+		if strings.Contains(message, "check-and-set") {
+			return true
+		}
+	}
+	return false
+}
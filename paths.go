@@ -0,0 +1,99 @@
+/******************************************************************************
+*
+*  Copyright 2024 SAP SE
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+)
+
+// cleanPath turns a user-supplied path to a secret into the form expected by
+// the vault client, without duplicate slashes and without a leading slash.
+func cleanPath(path string) string {
+	for strings.Contains(path, "//") {
+		path = strings.ReplaceAll(path, "//", "/")
+	}
+	return strings.TrimPrefix(path, "/")
+}
+
+// normalizeDir turns a user-supplied directory into the form used by the
+// lister, with a leading and a trailing slash.
+func normalizeDir(dir string) string {
+	dir = strings.Trim(cleanPath(dir), "/") + "/"
+	if dir != "/" {
+		dir = "/" + dir
+	}
+	return dir
+}
+
+// relativePath strips the leading slash from a path returned by the lister.
+func relativePath(listed string) string {
+	return strings.TrimPrefix(listed, "/")
+}
+
+// normalizeMount returns mount without surrounding or duplicate slashes and
+// rejects mounts that cannot be a mount path.
+func normalizeMount(mount string) (string, error) {
+	mount = strings.Trim(cleanPath(mount), "/")
+	if mount == "" {
+		return "", errors.New("the mount must not be empty")
+	}
+	for _, segment := range strings.Split(mount, "/") {
+		if segment == "." || segment == ".." {
+			return "", fmt.Errorf("invalid mount %q", mount)
+		}
+	}
+	return mount, nil
+}
+
+// normalizeMountFlag returns a flag action replacing the value of the mount
+// flag name by its normalized form, so that commands can read it as is.
+func normalizeMountFlag(name string) func(ctx *cli.Context, mount string) error {
+	return func(ctx *cli.Context, mount string) error {
+		mount, err := normalizeMount(mount)
+		if err != nil {
+			return err
+		}
+		return ctx.Set(name, mount)
+	}
+}
+
+// pathArgs returns the arguments of a command taking paths to secrets.
+func pathArgs(ctx *cli.Context) []string {
+	args := ctx.Args().Slice()
+	for idx, arg := range args {
+		args[idx] = cleanPath(arg)
+	}
+	return args
+}
+
+// splitMountPath splits a path prefixed with one of mounts, as printed by
+// listall --all-mounts, into the mount and the path of the secret. The longest
+// matching mount wins, since mounts can be nested.
+func splitMountPath(mounts []string, path string) (mount, secretPath string, ok bool) {
+	for _, m := range mounts {
+		if rest, found := strings.CutPrefix(path, m+"/"); found && len(m) > len(mount) {
+			mount, secretPath = m, rest
+		}
+	}
+	return mount, secretPath, mount != ""
+}
@@ -0,0 +1,165 @@
+/******************************************************************************
+*
+*  Copyright 2024 SAP SE
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/urfave/cli/v2"
+)
+
+func TestCleanPath(t *testing.T) {
+	cases := map[string]string{
+		"":               "",
+		"/":              "",
+		"team/db":        "team/db",
+		"/team/db":       "team/db",
+		"//team//db":     "team/db",
+		"team///db":      "team/db",
+		"team/dir/":      "team/dir/",
+		"team//dir//":    "team/dir/",
+		"/team/dir///db": "team/dir/db",
+	}
+	for input, expected := range cases {
+		if actual := cleanPath(input); actual != expected {
+			t.Errorf("cleanPath(%q) = %q, expected %q", input, actual, expected)
+		}
+	}
+}
+
+func TestNormalizeDir(t *testing.T) {
+	cases := map[string]string{
+		"":           "/",
+		"/":          "/",
+		"//":         "/",
+		"team":       "/team/",
+		"team/":      "/team/",
+		"/team/":     "/team/",
+		"//team//a/": "/team/a/",
+	}
+	for input, expected := range cases {
+		if actual := normalizeDir(input); actual != expected {
+			t.Errorf("normalizeDir(%q) = %q, expected %q", input, actual, expected)
+		}
+	}
+}
+
+func TestRelativePath(t *testing.T) {
+	cases := map[string]string{
+		"/team/db": "team/db",
+		"/team/":   "team/",
+		"team/db":  "team/db",
+		"":         "",
+		"/":        "",
+	}
+	for input, expected := range cases {
+		if actual := relativePath(input); actual != expected {
+			t.Errorf("relativePath(%q) = %q, expected %q", input, actual, expected)
+		}
+	}
+}
+
+func TestNormalizeMount(t *testing.T) {
+	cases := map[string]string{
+		"secrets":       "secrets",
+		"secrets/":      "secrets",
+		"/secrets/":     "secrets",
+		"team//secrets": "team/secrets",
+		"/team/kv//":    "team/kv",
+	}
+	for input, expected := range cases {
+		actual, err := normalizeMount(input)
+		if err != nil {
+			t.Errorf("normalizeMount(%q) failed: %s", input, err)
+			continue
+		}
+		if actual != expected {
+			t.Errorf("normalizeMount(%q) = %q, expected %q", input, actual, expected)
+		}
+	}
+
+	for _, input := range []string{"", "/", "//", "../secrets", "team/./kv"} {
+		if actual, err := normalizeMount(input); err == nil {
+			t.Errorf("normalizeMount(%q) = %q, expected an error", input, actual)
+		}
+	}
+}
+
+func TestNormalizeMountFlag(t *testing.T) {
+	var actual string
+	app := &cli.App{
+		Flags: []cli.Flag{
+			&cli.StringFlag{Name: "dst-mount", Action: normalizeMountFlag("dst-mount")},
+		},
+		Action: func(ctx *cli.Context) error {
+			actual = ctx.String("dst-mount")
+			return nil
+		},
+	}
+	if err := app.Run([]string{"mutavault", "--dst-mount=/team//kv/"}); err != nil {
+		t.Fatal(err)
+	}
+	if actual != "team/kv" {
+		t.Errorf("--dst-mount is %q, expected %q", actual, "team/kv")
+	}
+	if err := app.Run([]string{"mutavault", "--dst-mount=../kv"}); err == nil {
+		t.Error("expected an error for an invalid mount")
+	}
+}
+
+func TestValidateCustomMetasCleansPaths(t *testing.T) {
+	customMetas := []any{
+		map[string]any{"path": "//team//db", "owner": "a"},
+		map[string]any{"path": "/team/web", "owner": "b"},
+	}
+	entries, err := validateCustomMetas(customMetas, "path", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for idx, expected := range []string{"team/db", "team/web"} {
+		if entries[idx].path != expected {
+			t.Errorf("entry %d has path %q, expected %q", idx, entries[idx].path, expected)
+		}
+	}
+
+	// paths differing only in slashes are duplicates
+	customMetas = []any{
+		map[string]any{"path": "team/db"},
+		map[string]any{"path": "/team//db"},
+	}
+	if _, err := validateCustomMetas(customMetas, "path", false); err == nil {
+		t.Error("expected an error for duplicate paths")
+	}
+}
+
+func TestDecodeSecretsCleansPaths(t *testing.T) {
+	for _, input := range []string{
+		`[{"path": "/team//db", "data": {}}]`,
+		`{"path": "/team//db", "data": {}}`,
+	} {
+		secrets, err := decodeSecrets(strings.NewReader(input))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(secrets) != 1 || secrets[0].Path != "team/db" {
+			t.Errorf("decodeSecrets(%q) = %v, expected the path team/db", input, secrets)
+		}
+	}
+}
@@ -0,0 +1,252 @@
+/******************************************************************************
+*
+*  Copyright 2024 SAP SE
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/hashicorp/hcl"
+	"github.com/hashicorp/vault/api"
+	"github.com/urfave/cli/v2"
+)
+
+// aclRule is a single path stanza of an ACL policy.
+type aclRule struct {
+	Pattern      string   `json:"rule"         yaml:"rule"`
+	Capabilities []string `json:"capabilities" yaml:"capabilities"`
+}
+
+// aclPolicy is an ACL policy with its path rules.
+type aclPolicy struct {
+	Name  string
+	Rules []aclRule
+}
+
+// parseACLPolicy parses the path rules of a policy in either HCL or JSON.
+func parseACLPolicy(name, text string) (aclPolicy, error) {
+	// the root policy has no rules but grants everything
+	if name == "root" {
+		return aclPolicy{Name: name, Rules: []aclRule{{Pattern: "*", Capabilities: []string{"root"}}}}, nil
+	}
+	var parsed struct {
+		Paths map[string]struct {
+			Capabilities []string `hcl:"capabilities"`
+		} `hcl:"path"`
+	}
+	if err := hcl.Decode(&parsed, text); err != nil {
+		return aclPolicy{}, fmt.Errorf("failed to parse policy %s: %w", name, err)
+	}
+	policy := aclPolicy{Name: name, Rules: make([]aclRule, 0, len(parsed.Paths))}
+	for pattern, rule := range parsed.Paths {
+		policy.Rules = append(policy.Rules, aclRule{Pattern: pattern, Capabilities: rule.Capabilities})
+	}
+	return policy, nil
+}
+
+// fetchACLPolicies downloads and parses all ACL policies. Policies which the
+// token may not read are skipped with a warning.
+func fetchACLPolicies(ctx *cli.Context, client *api.Client) ([]aclPolicy, error) {
+	names, err := client.Sys().ListPoliciesWithContext(ctx.Context)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list ACL policies: %w", err)
+	}
+	slices.Sort(names)
+	result := mapConcurrently(ctx.Context, names, func(name string) (*aclPolicy, error) {
+		text, err := client.Sys().GetPolicyWithContext(ctx.Context, name)
+		var respErr *api.ResponseError
+		if errors.As(err, &respErr) && respErr.StatusCode == http.StatusForbidden {
+			slog.Warn("reading policy is forbidden, skipping it", "policy", name)
+			return nil, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read policy %s: %w", name, err)
+		}
+		policy, err := parseACLPolicy(name, text)
+		if err != nil {
+			return nil, err
+		}
+		return &policy, nil
+	})
+	policies := make([]aclPolicy, 0, len(names))
+	for _, r := range result {
+		if r.err != nil {
+			return nil, r.err
+		}
+		if r.value != nil {
+			policies = append(policies, *r.value)
+		}
+	}
+	return policies, nil
+}
+
+// matchesACLPattern reports whether the API path matches a policy path
+// pattern. A "+" matches exactly one path segment and a trailing "*" matches
+// any suffix, including further segments.
+func matchesACLPattern(pattern, path string) bool {
+	glob := strings.HasSuffix(pattern, "*")
+	patternSegments := strings.Split(strings.TrimSuffix(pattern, "*"), "/")
+	pathSegments := strings.Split(path, "/")
+	if len(pathSegments) < len(patternSegments) || (!glob && len(pathSegments) != len(patternSegments)) {
+		return false
+	}
+	last := len(patternSegments) - 1
+	for idx, segment := range patternSegments {
+		switch {
+		case segment == "+":
+			continue
+		case idx == last && glob:
+			if !strings.HasPrefix(pathSegments[idx], segment) {
+				return false
+			}
+		case segment != pathSegments[idx]:
+			return false
+		}
+	}
+	return true
+}
+
+// aclPatternPrecedes reports whether Vault prefers pattern a over pattern b
+// when both match the same path, following the documented priority rules.
+func aclPatternPrecedes(a, b string) bool {
+	firstWildcard := func(p string) int {
+		if idx := strings.IndexAny(p, "+*"); idx >= 0 {
+			return idx
+		}
+		return len(p)
+	}
+	if wa, wb := firstWildcard(a), firstWildcard(b); wa != wb {
+		return wa > wb
+	}
+	if ga, gb := strings.HasSuffix(a, "*"), strings.HasSuffix(b, "*"); ga != gb {
+		return gb
+	}
+	if pa, pb := strings.Count(a, "+"), strings.Count(b, "+"); pa != pb {
+		return pa < pb
+	}
+	if len(a) != len(b) {
+		return len(a) > len(b)
+	}
+	return a > b
+}
+
+// match returns the rule of the policy which applies to the API path, or nil
+// if no rule matches.
+func (p aclPolicy) match(path string) *aclRule {
+	var best *aclRule
+	for idx, rule := range p.Rules {
+		if matchesACLPattern(rule.Pattern, path) && (best == nil || aclPatternPrecedes(rule.Pattern, best.Pattern)) {
+			best = &p.Rules[idx]
+		}
+	}
+	return best
+}
+
+// policyMatch is a policy whose rule applies to a path.
+type policyMatch struct {
+	Policy  string `json:"policy" yaml:"policy"`
+	aclRule `yaml:",inline"`
+}
+
+// pathCoverage are the policies applying to a secret.
+type pathCoverage struct {
+	Path     string        `json:"path"     yaml:"path"`
+	Policies []policyMatch `json:"policies" yaml:"policies"`
+}
+
+// validatePolicyFlags checks the flags shared by all policy subcommands.
+func validatePolicyFlags(ctx *cli.Context) error {
+	if kvVersion := ctx.Int("kv-version"); kvVersion != 1 && kvVersion != 2 {
+		return fmt.Errorf("unsupported kv version %d", kvVersion)
+	}
+	if ctx.String("mount") == "" {
+		return fmt.Errorf("either --mount or the %s environment variable is required", mountEnvVar)
+	}
+	return normalizeMountFlag("mount")(ctx, ctx.String("mount"))
+}
+
+func policyCoverage(ctx *cli.Context) (err error) {
+	format := ctx.String("format")
+	if err := checkFormat(format, "text", "json", "yaml"); err != nil {
+		return err
+	}
+	capability := ctx.String("capability")
+	if capability != "" && !slices.Contains([]string{"create", "read", "update", "patch", "delete", "list"}, capability) {
+		return fmt.Errorf("unsupported capability %q", capability)
+	}
+	client, err := createClient(ctx)
+	if err != nil {
+		return err
+	}
+	out, err := openOutput(ctx)
+	if err != nil {
+		return err
+	}
+	defer closeOutput(out, &err)
+	paths, err := readPaths(ctx)
+	if err != nil {
+		return err
+	}
+	if len(paths) == 0 {
+		return errors.New("no paths were given")
+	}
+	policies, err := fetchACLPolicies(ctx, client)
+	if err != nil {
+		return err
+	}
+	mount, kvVersion := ctx.String("mount"), ctx.Int("kv-version")
+
+	coverage := make([]pathCoverage, 0, len(paths))
+	for _, path := range paths {
+		dataPath, listPath := apiPaths(mount, kvVersion, path)
+		apiPath := dataPath
+		if capability == "list" {
+			apiPath = listPath
+		}
+		c := pathCoverage{Path: path, Policies: make([]policyMatch, 0)}
+		for _, policy := range policies {
+			rule := policy.match(apiPath)
+			if rule == nil || (capability != "" && !hasCapability(rule.Capabilities, capability)) {
+				continue
+			}
+			c.Policies = append(c.Policies, policyMatch{Policy: policy.Name, aclRule: *rule})
+		}
+		coverage = append(coverage, c)
+	}
+
+	if format != "text" {
+		return encodeOutput(out, format, coverage)
+	}
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PATH\tPOLICY\tRULE\tCAPABILITIES")
+	for _, c := range coverage {
+		if len(c.Policies) == 0 {
+			fmt.Fprintf(w, "%s\t-\t-\t-\n", c.Path)
+		}
+		for _, m := range c.Policies {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", c.Path, m.Policy, m.Pattern, strings.Join(m.Capabilities, ","))
+		}
+	}
+	return w.Flush()
+}
@@ -0,0 +1,125 @@
+/******************************************************************************
+*
+*  Copyright 2024 SAP SE
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package main
+
+import "testing"
+
+func TestMatchesACLPattern(t *testing.T) {
+	cases := []struct {
+		pattern, path string
+		expected      bool
+	}{
+		{"kv/data/team/db", "kv/data/team/db", true},
+		{"kv/data/team/db", "kv/data/team/db2", false},
+		{"kv/data/team/db", "kv/data/team", false},
+		{"kv/data/*", "kv/data/team/db", true},
+		{"kv/data/*", "kv/data/", true},
+		{"kv/data/*", "kv/metadata/team", false},
+		{"kv/data/te*", "kv/data/team/db", true},
+		{"kv/data/te*", "kv/data/other", false},
+		{"*", "kv/data/team/db", true},
+		{"kv/data/+/db", "kv/data/team/db", true},
+		{"kv/data/+/db", "kv/data/team/sub/db", false},
+		{"kv/data/+/db", "kv/data/team/app", false},
+		{"kv/+/team/+", "kv/data/team/db", true},
+		{"kv/+/team/+", "kv/data/team/db/x", false},
+		{"kv/data/+/*", "kv/data/team/sub/db", true},
+		{"kv/data/+/*", "kv/data/team", false},
+	}
+	for _, c := range cases {
+		if actual := matchesACLPattern(c.pattern, c.path); actual != c.expected {
+			t.Errorf("matchesACLPattern(%q, %q) = %t, expected %t", c.pattern, c.path, actual, c.expected)
+		}
+	}
+}
+
+func TestACLPatternPrecedes(t *testing.T) {
+	// each pair is ordered from higher to lower priority
+	cases := [][2]string{
+		// the first wildcard occurs later
+		{"kv/data/team/*", "kv/data/+/db"},
+		{"kv/data/team/+", "kv/data/*"},
+		// exact paths win over globs
+		{"kv/data/team/db", "kv/data/team/db*"},
+		// fewer + segments
+		{"kv/data/+/db/x", "kv/data/+/+/x"},
+		// longer paths
+		{"kv/data/team/db", "kv/data/team/d"},
+		// lexicographically larger paths
+		{"kv/data/team/b", "kv/data/team/a"},
+	}
+	for _, c := range cases {
+		if !aclPatternPrecedes(c[0], c[1]) {
+			t.Errorf("expected %q to precede %q", c[0], c[1])
+		}
+		if aclPatternPrecedes(c[1], c[0]) {
+			t.Errorf("expected %q not to precede %q", c[1], c[0])
+		}
+	}
+}
+
+func TestACLPolicyMatch(t *testing.T) {
+	policy, err := parseACLPolicy("team", `
+path "kv/data/*" { capabilities = ["read"] }
+path "kv/data/team/*" { capabilities = ["deny"] }
+path "kv/data/+/public" { capabilities = ["read", "list"] }
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := map[string]string{
+		"kv/data/team/db":      "kv/data/team/*",
+		"kv/data/team/public":  "kv/data/team/*",
+		"kv/data/other/public": "kv/data/+/public",
+		"kv/data/other/db":     "kv/data/*",
+		"kv/metadata/other":    "",
+	}
+	for path, expected := range cases {
+		rule := policy.match(path)
+		actual := ""
+		if rule != nil {
+			actual = rule.Pattern
+		}
+		if actual != expected {
+			t.Errorf("match(%q) = %q, expected %q", path, actual, expected)
+		}
+	}
+	if _, err := parseACLPolicy("broken", `path "kv/*" {`); err == nil {
+		t.Error("expected an error for a malformed policy")
+	}
+}
+
+func TestHasCapability(t *testing.T) {
+	cases := []struct {
+		capabilities []string
+		capability   string
+		expected     bool
+	}{
+		{[]string{"read", "list"}, "read", true},
+		{[]string{"read", "list"}, "update", false},
+		{[]string{"root"}, "delete", true},
+		{[]string{"read", "deny"}, "read", false},
+		{nil, "read", false},
+	}
+	for _, c := range cases {
+		if actual := hasCapability(c.capabilities, c.capability); actual != c.expected {
+			t.Errorf("hasCapability(%v, %q) = %t, expected %t", c.capabilities, c.capability, actual, c.expected)
+		}
+	}
+}
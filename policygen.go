@@ -0,0 +1,226 @@
+/******************************************************************************
+*
+*  Copyright 2024 SAP SE
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+)
+
+// dataCapabilities are the capabilities which apply to the data of a secret,
+// as opposed to list which applies to its metadata.
+var dataCapabilities = []string{"create", "read", "update", "patch", "delete"}
+
+// collapsePaths returns patterns covering exactly the given paths among the
+// existing secrets. Directories whose secrets are all given become "dir/*"
+// and paths which only differ in one segment become "a/+/c" if no other
+// existing secret matches. Given paths which do not exist are kept as-is.
+func collapsePaths(given, existing []string) []string {
+	givenSet := make(map[string]bool, len(given))
+	for _, path := range given {
+		givenSet[path] = true
+	}
+	// number of existing and of given secrets below each directory
+	total := make(map[string]int)
+	covered := make(map[string]int)
+	for _, path := range existing {
+		dirs := []string{""}
+		for idx := range len(path) {
+			if path[idx] == '/' {
+				dirs = append(dirs, path[:idx+1])
+			}
+		}
+		for _, dir := range dirs {
+			total[dir]++
+			if givenSet[path] {
+				covered[dir]++
+			}
+		}
+	}
+	collapsible := func(dir string) bool {
+		return total[dir] >= 2 && total[dir] == covered[dir]
+	}
+
+	patterns := make(map[string]bool)
+	remaining := make([]string, 0)
+	for path := range givenSet {
+		// the shortest fully covered directory wins
+		collapsed := collapsible("")
+		if collapsed {
+			patterns["*"] = true
+		}
+		for idx := 0; !collapsed && idx < len(path); idx++ {
+			if path[idx] == '/' && collapsible(path[:idx+1]) {
+				patterns[path[:idx+1]+"*"], collapsed = true, true
+			}
+		}
+		if !collapsed {
+			remaining = append(remaining, path)
+		}
+	}
+
+	// group the remaining paths by every pattern replacing one segment with "+"
+	groups := make(map[string][]string)
+	for _, path := range remaining {
+		segments := strings.Split(path, "/")
+		for idx := range segments {
+			pattern := strings.Join(slices.Concat(segments[:idx], []string{"+"}, segments[idx+1:]), "/")
+			groups[pattern] = append(groups[pattern], path)
+		}
+	}
+	candidates := make([]string, 0, len(groups))
+	for pattern, paths := range groups {
+		if len(paths) >= 2 {
+			candidates = append(candidates, pattern)
+		}
+	}
+	// prefer the patterns covering the most paths
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		return len(groups[a]) > len(groups[b]) || (len(groups[a]) == len(groups[b]) && a < b)
+	})
+	done := make(map[string]bool, len(remaining))
+	for _, pattern := range candidates {
+		if slices.ContainsFunc(groups[pattern], func(path string) bool { return done[path] }) {
+			continue
+		}
+		if slices.ContainsFunc(existing, func(path string) bool {
+			return !givenSet[path] && matchesACLPattern(pattern, path)
+		}) {
+			continue
+		}
+		patterns[pattern] = true
+		for _, path := range groups[pattern] {
+			done[path] = true
+		}
+	}
+	for _, path := range remaining {
+		if !done[path] {
+			patterns[path] = true
+		}
+	}
+
+	result := make([]string, 0, len(patterns))
+	for pattern := range patterns {
+		result = append(result, pattern)
+	}
+	slices.Sort(result)
+	return result
+}
+
+// writePolicy writes the rules, which map API path patterns to capabilities,
+// as a policy in either "hcl" or "json".
+func writePolicy(w io.Writer, format string, rules map[string][]string) error {
+	patterns := make([]string, 0, len(rules))
+	for pattern := range rules {
+		patterns = append(patterns, pattern)
+	}
+	slices.Sort(patterns)
+	if format == "json" {
+		paths := make(map[string]any, len(rules))
+		for _, pattern := range patterns {
+			paths[pattern] = map[string][]string{"capabilities": rules[pattern]}
+		}
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(map[string]any{"path": paths})
+	}
+	for idx, pattern := range patterns {
+		if idx > 0 {
+			fmt.Fprintln(w)
+		}
+		quoted := make([]string, 0, len(rules[pattern]))
+		for _, capability := range rules[pattern] {
+			quoted = append(quoted, fmt.Sprintf("%q", capability))
+		}
+		_, err := fmt.Fprintf(w, "path %q {\n  capabilities = [%s]\n}\n", pattern, strings.Join(quoted, ", "))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func policyGenerate(ctx *cli.Context) (err error) {
+	format := ctx.String("format")
+	if err := checkFormat(format, "hcl", "json"); err != nil {
+		return err
+	}
+	capabilities := ctx.StringSlice("capability")
+	for _, capability := range capabilities {
+		if capability != "list" && !slices.Contains(dataCapabilities, capability) {
+			return fmt.Errorf("unsupported capability %q", capability)
+		}
+	}
+	client, err := createClient(ctx)
+	if err != nil {
+		return err
+	}
+	out, err := openOutput(ctx)
+	if err != nil {
+		return err
+	}
+	defer closeOutput(out, &err)
+	paths, err := readPaths(ctx)
+	if err != nil {
+		return err
+	}
+	if len(paths) == 0 {
+		return errors.New("no paths were given")
+	}
+
+	patterns := paths
+	if ctx.Bool("collapse") {
+		lister := newLister(ctx, client)
+		// skipped directories could hide secrets matched by a collapsed pattern
+		lister.forbidden = "fail"
+		listed, err := lister.listSecretDirRecurse(ctx.Context, "/")
+		if err != nil {
+			return err
+		}
+		existing := make([]string, 0, len(listed))
+		for _, path := range listed {
+			existing = append(existing, relativePath(path))
+		}
+		patterns = collapsePaths(paths, existing)
+	}
+
+	mount, kvVersion := ctx.String("mount"), ctx.Int("kv-version")
+	rules := make(map[string][]string)
+	for _, pattern := range patterns {
+		dataPath, listPath := apiPaths(mount, kvVersion, pattern)
+		for _, capability := range capabilities {
+			apiPath := dataPath
+			if capability == "list" {
+				apiPath = listPath
+			}
+			if !slices.Contains(rules[apiPath], capability) {
+				rules[apiPath] = append(rules[apiPath], capability)
+			}
+		}
+	}
+	return writePolicy(out, format, rules)
+}
@@ -0,0 +1,119 @@
+/******************************************************************************
+*
+*  Copyright 2024 SAP SE
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package main
+
+import (
+	"bytes"
+	"slices"
+	"testing"
+)
+
+func TestCollapsePaths(t *testing.T) {
+	existing := []string{
+		"teams/a/db", "teams/a/app",
+		"teams/b/db", "teams/b/app",
+		"teams/c/db",
+		"other/x", "other/y",
+		"other/deep/z",
+		"top",
+	}
+	cases := []struct {
+		given    []string
+		expected []string
+	}{
+		// a + glob is safe since every teams/*/db is given
+		{[]string{"teams/a/db", "teams/b/db", "teams/c/db"}, []string{"teams/+/db"}},
+		// teams/c/db would also match teams/+/db
+		{[]string{"teams/a/db", "teams/b/db"}, []string{"teams/a/db", "teams/b/db"}},
+		// fully covered directories become * globs, nonexistent paths are kept
+		{[]string{"teams/a/db", "teams/a/app", "nope"}, []string{"nope", "teams/a/*"}},
+		{[]string{"other/x", "other/y", "other/deep/z"}, []string{"other/*"}},
+		// other/deep/z is not given
+		{[]string{"other/x", "other/y"}, []string{"other/+"}},
+		// a directory with a single secret is not collapsed
+		{[]string{"other/deep/z"}, []string{"other/deep/z"}},
+		{existing, []string{"*"}},
+		{[]string{"top", "top"}, []string{"top"}},
+	}
+	for _, c := range cases {
+		actual := collapsePaths(c.given, existing)
+		if !slices.Equal(actual, c.expected) {
+			t.Errorf("collapsePaths(%v) = %v, expected %v", c.given, actual, c.expected)
+		}
+		// no pattern may grant access to a secret which was not given
+		for _, pattern := range actual {
+			for _, path := range existing {
+				if matchesACLPattern(pattern, path) && !slices.Contains(c.given, path) {
+					t.Errorf("collapsePaths(%v) returned %q, which also covers %q", c.given, pattern, path)
+				}
+			}
+		}
+		// and every given secret must still be covered
+		for _, path := range c.given {
+			if !slices.ContainsFunc(actual, func(pattern string) bool { return matchesACLPattern(pattern, path) }) {
+				t.Errorf("collapsePaths(%v) = %v does not cover %q", c.given, actual, path)
+			}
+		}
+	}
+}
+
+func TestWritePolicy(t *testing.T) {
+	rules := map[string][]string{
+		"kv/metadata/teams/*": {"list"},
+		"kv/data/teams/+/db":  {"read", "update"},
+	}
+	var buf bytes.Buffer
+	if err := writePolicy(&buf, "hcl", rules); err != nil {
+		t.Fatal(err)
+	}
+	expected := `path "kv/data/teams/+/db" {
+  capabilities = ["read", "update"]
+}
+
+path "kv/metadata/teams/*" {
+  capabilities = ["list"]
+}
+`
+	if buf.String() != expected {
+		t.Errorf("writePolicy() = %q, expected %q", buf.String(), expected)
+	}
+	// the generated policy must parse to the same rules
+	policy, err := parseACLPolicy("generated", buf.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf.Reset()
+	if err := writePolicy(&buf, "json", rules); err != nil {
+		t.Fatal(err)
+	}
+	fromJSON, err := parseACLPolicy("generated", buf.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []aclPolicy{policy, fromJSON} {
+		if len(p.Rules) != len(rules) {
+			t.Errorf("parsed %d rules, expected %d", len(p.Rules), len(rules))
+		}
+		for _, rule := range p.Rules {
+			if !slices.Equal(rule.Capabilities, rules[rule.Pattern]) {
+				t.Errorf("rule %q has capabilities %v, expected %v", rule.Pattern, rule.Capabilities, rules[rule.Pattern])
+			}
+		}
+	}
+}
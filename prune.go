@@ -0,0 +1,118 @@
+/******************************************************************************
+*
+*  Copyright 2024 SAP SE
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/urfave/cli/v2"
+)
+
+func pruneVersions(ctx *cli.Context) error {
+	keep := ctx.Int("keep")
+	var olderThan time.Duration
+	if ctx.IsSet("older-than") {
+		var err error
+		olderThan, err = parseDuration(ctx.String("older-than"))
+		if err != nil {
+			return fmt.Errorf("invalid --older-than: %w", err)
+		}
+	}
+	if keep <= 0 && olderThan <= 0 {
+		return errors.New("either --keep or --older-than is required")
+	}
+	if keep < 0 || olderThan < 0 {
+		return errors.New("--keep and --older-than must not be negative")
+	}
+	dryRun := ctx.Bool("dry-run")
+	client, err := createClient(ctx)
+	if err != nil {
+		return err
+	}
+	dirs := pathArgs(ctx)
+	if len(dirs) == 0 {
+		dirs = []string{""}
+	}
+	paths, err := listRecursive(ctx, client, dirs)
+	if err != nil {
+		return err
+	}
+	sort.Strings(paths)
+	if !dryRun {
+		if err := confirm(ctx, "permanently destroy old versions of", len(paths)); err != nil {
+			return err
+		}
+	}
+
+	cutoff := time.Now().Add(-olderThan)
+	kv := client.KVv2(ctx.String("mount"))
+	result := mapConcurrently(ctx.Context, paths, func(path string) ([]int, error) {
+		meta, err := kv.GetMetadata(ctx.Context, path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get metadata for %s: %w", path, err)
+		}
+		remaining := make([]versionInfo, 0, len(meta.Versions))
+		for _, v := range meta.Versions {
+			if !v.Destroyed {
+				remaining = append(remaining, versionInfo{Version: v.Version, CreatedTime: v.CreatedTime})
+			}
+		}
+		// newest first, so that the versions to keep come first
+		sort.Slice(remaining, func(i, j int) bool {
+			return remaining[i].Version > remaining[j].Version
+		})
+		prune := make([]int, 0)
+		for idx, v := range remaining {
+			// the current version is never destroyed
+			if v.Version == meta.CurrentVersion || idx < keep {
+				continue
+			}
+			if olderThan > 0 && v.CreatedTime.After(cutoff) {
+				continue
+			}
+			prune = append(prune, v.Version)
+		}
+		if len(prune) == 0 || dryRun {
+			return prune, nil
+		}
+		if err := kv.Destroy(ctx.Context, path, prune); err != nil {
+			return nil, fmt.Errorf("failed to destroy versions %s of %s: %w", joinVersions(prune), path, err)
+		}
+		return prune, nil
+	})
+
+	verb := "destroyed"
+	if dryRun {
+		verb = "would destroy"
+	}
+	errs := make([]error, 0)
+	for idx, r := range result {
+		if r.err != nil {
+			errs = append(errs, r.err)
+			continue
+		}
+		if len(r.value) > 0 {
+			fmt.Printf("%s: %s versions %s\n", paths[idx], verb, joinVersions(r.value))
+		}
+	}
+	return joinErrors(errs, len(result)-len(errs))
+}
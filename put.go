@@ -0,0 +1,131 @@
+/******************************************************************************
+*
+*  Copyright 2024 SAP SE
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"unicode"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/urfave/cli/v2"
+	"golang.org/x/sync/semaphore"
+)
+
+func put(ctx *cli.Context) error {
+	client, err := createClient(ctx)
+	if err != nil {
+		return err
+	}
+	secrets, err := decodeSecrets(os.Stdin)
+	if err != nil {
+		return err
+	}
+	opts := make([]api.KVOption, 0)
+	if ctx.IsSet("cas") {
+		opts = append(opts, api.WithCheckAndSet(ctx.Int("cas")))
+	}
+	kv := client.KVv2(ctx.String("mount"))
+	result := make([]Result[string], 0)
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	sema := semaphore.NewWeighted(concurrency)
+
+	for _, secret := range secrets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := sema.Acquire(ctx.Context, 1); err != nil {
+				mutex.Lock()
+				defer mutex.Unlock()
+				result = append(result, Result[string]{err: err})
+				return
+			}
+			written, err := kv.Put(ctx.Context, secret.Path, secret.Data, opts...)
+			sema.Release(1)
+			mutex.Lock()
+			defer mutex.Unlock()
+			if err != nil {
+				result = append(result, Result[string]{err: fmt.Errorf("failed to write secret %s: %w", secret.Path, err)})
+				return
+			}
+			result = append(result, Result[string]{value: fmt.Sprintf("%s: version %d", secret.Path, written.VersionMetadata.Version)})
+		}()
+	}
+
+	wg.Wait()
+	errs := make([]error, 0)
+	for _, r := range result {
+		if r.err != nil {
+			errs = append(errs, r.err)
+			continue
+		}
+		fmt.Println(r.value)
+	}
+	return joinErrors(errs, len(result)-len(errs))
+}
+
+// decodeSecrets reads either a JSON array of secrets or newline-delimited
+// JSON objects as printed by get -ndjson.
+func decodeSecrets(r io.Reader) ([]secretData, error) {
+	buffered := bufio.NewReader(r)
+	decoder := json.NewDecoder(buffered)
+	secrets := make([]secretData, 0)
+	first, err := peekNonSpace(buffered)
+	if err != nil || first == '[' {
+		err := decoder.Decode(&secrets)
+		for idx := range secrets {
+			secrets[idx].Path = cleanPath(secrets[idx].Path)
+		}
+		return secrets, err
+	}
+	for {
+		var secret secretData
+		err := decoder.Decode(&secret)
+		if errors.Is(err, io.EOF) {
+			return secrets, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode secret %d: %w", len(secrets), err)
+		}
+		secret.Path = cleanPath(secret.Path)
+		secrets = append(secrets, secret)
+	}
+}
+
+// peekNonSpace returns the first byte of r that is not whitespace without consuming it.
+func peekNonSpace(r *bufio.Reader) (byte, error) {
+	for {
+		b, err := r.Peek(1)
+		if err != nil {
+			return 0, err
+		}
+		if !unicode.IsSpace(rune(b[0])) {
+			return b[0], nil
+		}
+		if _, err := r.ReadByte(); err != nil {
+			return 0, err
+		}
+	}
+}
@@ -0,0 +1,244 @@
+/******************************************************************************
+*
+*  Copyright 2024 SAP SE
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// metaExpr is a parsed --where expression of kv findmetas.
+type metaExpr interface {
+	eval(customMeta map[string]any) bool
+}
+
+type andExpr struct{ left, right metaExpr }
+
+func (e andExpr) eval(m map[string]any) bool { return e.left.eval(m) && e.right.eval(m) }
+
+type orExpr struct{ left, right metaExpr }
+
+func (e orExpr) eval(m map[string]any) bool { return e.left.eval(m) || e.right.eval(m) }
+
+type notExpr struct{ inner metaExpr }
+
+func (e notExpr) eval(m map[string]any) bool { return !e.inner.eval(m) }
+
+// existsExpr is a bare key, which is true if the key is set.
+type existsExpr struct{ key string }
+
+func (e existsExpr) eval(m map[string]any) bool {
+	_, ok := m[e.key]
+	return ok
+}
+
+// compareExpr compares the value of a key with a string. A missing key is
+// unequal to every value and matches no regular expression.
+type compareExpr struct {
+	key   string
+	op    string
+	value string
+	regex *regexp.Regexp
+}
+
+func (e compareExpr) eval(m map[string]any) bool {
+	raw, ok := m[e.key]
+	value := fmt.Sprint(raw)
+	switch e.op {
+	case "==":
+		return ok && value == e.value
+	case "!=":
+		return !ok || value != e.value
+	case "=~":
+		return ok && e.regex.MatchString(value)
+	default: // "!~"
+		return !ok || !e.regex.MatchString(value)
+	}
+}
+
+// parseMetaExpr parses expressions like `owner == "team-x" && env != "prod"`.
+// Supported are the comparisons ==, !=, =~ and !~ (regular expressions)
+// between a key and a quoted string, bare keys which test for existence,
+// and &&, || and ! with parentheses.
+func parseMetaExpr(input string) (metaExpr, error) {
+	tokens, err := tokenizeMetaExpr(input)
+	if err != nil {
+		return nil, err
+	}
+	p := &metaExprParser{tokens: tokens}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %s", p.tokens[p.pos].text)
+	}
+	return expr, nil
+}
+
+type metaToken struct {
+	// one of "key", "string" or the operator itself
+	kind string
+	text string
+}
+
+func tokenizeMetaExpr(input string) ([]metaToken, error) {
+	tokens := make([]metaToken, 0)
+	for pos := 0; pos < len(input); {
+		c := rune(input[pos])
+		switch {
+		case unicode.IsSpace(c):
+			pos++
+		case c == '"':
+			end := pos + 1
+			for end < len(input) && input[end] != '"' {
+				if input[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(input) {
+				return nil, errors.New("unterminated string")
+			}
+			value, err := strconv.Unquote(input[pos : end+1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid string %s: %w", input[pos:end+1], err)
+			}
+			tokens = append(tokens, metaToken{"string", value})
+			pos = end + 1
+		case isKeyChar(c):
+			end := pos
+			for end < len(input) && isKeyChar(rune(input[end])) {
+				end++
+			}
+			tokens = append(tokens, metaToken{"key", input[pos:end]})
+			pos = end
+		default:
+			op := ""
+			for _, candidate := range []string{"&&", "||", "==", "!=", "=~", "!~", "!", "(", ")"} {
+				if strings.HasPrefix(input[pos:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("unexpected character %q", c)
+			}
+			tokens = append(tokens, metaToken{op, op})
+			pos += len(op)
+		}
+	}
+	return tokens, nil
+}
+
+func isKeyChar(c rune) bool {
+	return c < unicode.MaxASCII && (unicode.IsLetter(c) || unicode.IsDigit(c) || c == '_' || c == '-' || c == '.' || c == '/')
+}
+
+type metaExprParser struct {
+	tokens []metaToken
+	pos    int
+}
+
+func (p *metaExprParser) accept(kind string) bool {
+	if p.pos < len(p.tokens) && p.tokens[p.pos].kind == kind {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *metaExprParser) parseOr() (metaExpr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("||") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orExpr{left, right}
+	}
+	return left, nil
+}
+
+func (p *metaExprParser) parseAnd() (metaExpr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("&&") {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = andExpr{left, right}
+	}
+	return left, nil
+}
+
+func (p *metaExprParser) parseUnary() (metaExpr, error) {
+	if p.accept("!") {
+		inner, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notExpr{inner}, nil
+	}
+	if p.accept("(") {
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.accept(")") {
+			return nil, errors.New("missing closing parenthesis")
+		}
+		return expr, nil
+	}
+	if !p.accept("key") {
+		if p.pos < len(p.tokens) {
+			return nil, fmt.Errorf("expected a key instead of %s", p.tokens[p.pos].text)
+		}
+		return nil, errors.New("unexpected end of expression")
+	}
+	key := p.tokens[p.pos-1].text
+	for _, op := range []string{"==", "!=", "=~", "!~"} {
+		if !p.accept(op) {
+			continue
+		}
+		if !p.accept("string") {
+			return nil, fmt.Errorf("expected a quoted string after %s %s", key, op)
+		}
+		expr := compareExpr{key: key, op: op, value: p.tokens[p.pos-1].text}
+		if op == "=~" || op == "!~" {
+			var err error
+			expr.regex, err = regexp.Compile(expr.value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid regular expression for %s: %w", key, err)
+			}
+		}
+		return expr, nil
+	}
+	return existsExpr{key}, nil
+}
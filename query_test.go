@@ -0,0 +1,83 @@
+/******************************************************************************
+*
+*  Copyright 2024 SAP SE
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package main
+
+import "testing"
+
+func TestParseMetaExpr(t *testing.T) {
+	customMeta := map[string]any{"owner": "team-x", "env": "prod", "tier": "1", "note": `say "hi"`}
+	cases := map[string]bool{
+		`owner == "team-x"`:                 true,
+		`owner == "team-y"`:                 false,
+		`owner != "team-y"`:                 true,
+		`missing != "x"`:                    true,
+		`missing == ""`:                     false,
+		`owner =~ "^team-"`:                 true,
+		`owner !~ "^team-"`:                 false,
+		`missing !~ "x"`:                    true,
+		`owner`:                             true,
+		`missing`:                           false,
+		`!missing`:                          true,
+		`tier == "1"`:                       true,
+		`note == "say \"hi\""`:              true,
+		`owner=="team-x"&&env=="prod"`:      true,
+		`env == "dev" || owner == "team-x"`: true,
+		`env == "dev" || owner == "team-x" && env`: true,
+		// && binds tighter than ||
+		`owner == "team-x" || env == "dev" && missing`:   true,
+		`(owner == "team-x" || env == "dev") && missing`: false,
+		`!(env == "prod")`:        false,
+		`!!owner`:                 true,
+		`!owner || env == "prod"`: true,
+	}
+	for input, expected := range cases {
+		expr, err := parseMetaExpr(input)
+		if err != nil {
+			t.Errorf("parseMetaExpr(%s) failed: %s", input, err)
+			continue
+		}
+		if actual := expr.eval(customMeta); actual != expected {
+			t.Errorf("%s evaluated to %t, expected %t", input, actual, expected)
+		}
+	}
+}
+
+func TestParseMetaExprRejectsMalformedInput(t *testing.T) {
+	inputs := []string{
+		``,
+		`owner ==`,
+		`== "x"`,
+		`owner == team`,
+		`owner == "unterminated`,
+		`owner == "x" &&`,
+		`(owner == "x"`,
+		`owner == "x")`,
+		`owner "x"`,
+		`owner = "x"`,
+		`owner == "x" & env`,
+		`owner =~ "("`,
+		`owner == "x" env`,
+		`owner # "x"`,
+	}
+	for _, input := range inputs {
+		if _, err := parseMetaExpr(input); err == nil {
+			t.Errorf("parseMetaExpr(%s) succeeded, expected an error", input)
+		}
+	}
+}
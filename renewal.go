@@ -0,0 +1,136 @@
+/******************************************************************************
+*
+*  Copyright 2024 SAP SE
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+)
+
+// tokenRenewer renews the token in the background so that long-running
+// commands on large mounts do not fail once the initial TTL runs out.
+type tokenRenewer struct {
+	once  sync.Once
+	mutex sync.Mutex
+	// the last renewal failure, if any
+	err error
+}
+
+// tokenRenewal is shared by all clients since they use the same token.
+var tokenRenewal tokenRenewer
+
+// start begins renewing the token of client unless it is already renewed.
+func (r *tokenRenewer) start(ctx context.Context, client *api.Client) {
+	r.once.Do(func() {
+		secret, err := client.Auth().Token().LookupSelfWithContext(ctx)
+		if err != nil {
+			// the token might not be allowed to look itself up, the commands
+			// then report their own errors once it expires
+			slog.Debug("not renewing token, lookup failed", "error", err)
+			return
+		}
+		renewable, err := secret.TokenIsRenewable()
+		if err != nil || !renewable {
+			return
+		}
+		ttl, err := secret.TokenTTL()
+		if err != nil || ttl <= 0 {
+			return
+		}
+		// renewing counts as a use of tokens with a limited number of uses
+		if uses, err := secret.TokenRemainingUses(); err != nil || uses > 0 {
+			return
+		}
+		// the remaining TTL is shorter than the TTL the token was created
+		// with, which is what a renewal normally grants
+		increment := ttl
+		if creationTTL, ok := secret.Data["creation_ttl"].(json.Number); ok {
+			if seconds, err := creationTTL.Int64(); err == nil && seconds > 0 {
+				increment = time.Duration(seconds) * time.Second
+			}
+		}
+		go r.run(ctx, client, ttl, increment)
+	})
+}
+
+// run renews the token by increment whenever two thirds of its TTL have passed
+// until ctx is done, the token cannot be renewed any further or it has expired.
+func (r *tokenRenewer) run(ctx context.Context, client *api.Client, ttl, increment time.Duration) {
+	expiry := time.Now().Add(ttl)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Until(expiry) * 2 / 3):
+		}
+		secret, err := client.Auth().Token().RenewSelfWithContext(ctx, int(increment.Seconds()))
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			slog.Error("failed to renew token, requests will fail once it expires", "expires", expiry.Format(time.RFC3339), "error", err)
+			r.fail(fmt.Errorf("failed to renew token, it expires at %s: %w", expiry.Format(time.RFC3339), err))
+			if time.Until(expiry) < time.Second {
+				return
+			}
+			continue
+		}
+		renewed, err := secret.TokenTTL()
+		if err != nil || renewed <= 0 {
+			slog.Error("renewing the token returned no TTL, requests will fail once it expires", "expires", expiry.Format(time.RFC3339))
+			r.fail(fmt.Errorf("renewing the token returned no TTL, it expires at %s", expiry.Format(time.RFC3339)))
+			return
+		}
+		previous := expiry
+		expiry = time.Now().Add(renewed)
+		slog.Debug("renewed token", "ttl", renewed)
+		if reachedMaxTTL(renewed, increment, previous, expiry) {
+			slog.Warn("token reached its max TTL, requests will fail once it expires", "expires", expiry.Format(time.RFC3339))
+			return
+		}
+	}
+}
+
+// reachedMaxTTL reports whether a renewal was capped by the max TTL of the
+// token, so that renewing it again cannot extend it any further. This is the
+// case if the renewal granted less than the requested increment or did not
+// move the expiry from previous to renewed later.
+func reachedMaxTTL(ttl, increment time.Duration, previous, renewed time.Time) bool {
+	return ttl < increment || !renewed.After(previous)
+}
+
+// fail records a renewal failure for the final error message.
+func (r *tokenRenewer) fail(err error) {
+	r.mutex.Lock()
+	r.err = err
+	r.mutex.Unlock()
+}
+
+// failure returns the last renewal failure, if any.
+func (r *tokenRenewer) failure() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.err
+}
@@ -0,0 +1,45 @@
+/******************************************************************************
+*
+*  Copyright 2024 SAP SE
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReachedMaxTTL(t *testing.T) {
+	now := time.Now()
+	cases := []struct {
+		name      string
+		ttl       time.Duration
+		increment time.Duration
+		previous  time.Time
+		renewed   time.Time
+		expected  bool
+	}{
+		{"full increment", time.Hour, time.Hour, now.Add(20 * time.Minute), now.Add(time.Hour), false},
+		{"capped increment", 10 * time.Minute, time.Hour, now.Add(20 * time.Minute), now.Add(10 * time.Minute), true},
+		{"expiry unchanged", time.Hour, time.Hour, now.Add(time.Hour), now.Add(time.Hour), true},
+	}
+	for _, c := range cases {
+		if actual := reachedMaxTTL(c.ttl, c.increment, c.previous, c.renewed); actual != c.expected {
+			t.Errorf("%s: reachedMaxTTL() = %t, expected %t", c.name, actual, c.expected)
+		}
+	}
+}
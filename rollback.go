@@ -0,0 +1,77 @@
+/******************************************************************************
+*
+*  Copyright 2024 SAP SE
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v2"
+)
+
+func rollback(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return errors.New("expected exactly one path argument")
+	}
+	path := cleanPath(ctx.Args().First())
+	version := ctx.Int("to-version")
+	client, err := createClient(ctx)
+	if err != nil {
+		return err
+	}
+	kv := client.KVv2(ctx.String("mount"))
+	meta, err := kv.GetMetadata(ctx.Context, path)
+	if err != nil {
+		return fmt.Errorf("failed to get metadata for %s: %w", path, err)
+	}
+	versionMeta, ok := meta.Versions[strconv.Itoa(version)]
+	switch {
+	case !ok:
+		return fmt.Errorf("version %d of %s does not exist", version, path)
+	case versionMeta.Destroyed:
+		return fmt.Errorf("version %d of %s is destroyed", version, path)
+	case isDeleted(versionMeta):
+		return fmt.Errorf("version %d of %s is deleted", version, path)
+	}
+	secret, err := kv.GetVersion(ctx.Context, path, version)
+	if err != nil {
+		return fmt.Errorf("failed to read version %d of %s: %w", version, path, err)
+	}
+
+	if ctx.Bool("dry-run") {
+		keys := make([]string, 0, len(secret.Data))
+		for key := range secret.Data {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		fmt.Printf("would restore version %d of %s created at %s as version %d with keys: %s\n",
+			version, path, versionMeta.CreatedTime.Format(time.RFC3339), meta.CurrentVersion+1, strings.Join(keys, ", "))
+		return nil
+	}
+	written, err := kv.Put(ctx.Context, path, secret.Data)
+	if err != nil {
+		return fmt.Errorf("failed to write secret %s: %w", path, err)
+	}
+	fmt.Printf("restored version %d of %s as version %d\n", version, path, written.VersionMetadata.Version)
+	return nil
+}
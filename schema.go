@@ -0,0 +1,250 @@
+/******************************************************************************
+*
+*  Copyright 2024 SAP SE
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// jsonSchema is the subset of JSON Schema needed to describe custom metadata:
+// type, enum, const, pattern, minLength, maxLength, required, properties and
+// additionalProperties. Other keywords are rejected instead of being silently
+// ignored, except for annotations which do not affect validation.
+type jsonSchema struct {
+	Type       any                    `json:"type"`
+	Enum       []any                  `json:"enum"`
+	Const      json.RawMessage        `json:"const"`
+	Pattern    string                 `json:"pattern"`
+	MinLength  *int                   `json:"minLength"`
+	MaxLength  *int                   `json:"maxLength"`
+	Required   []string               `json:"required"`
+	Properties map[string]*jsonSchema `json:"properties"`
+	// either a boolean or a schema
+	AdditionalProperties json.RawMessage `json:"additionalProperties"`
+
+	// annotations, which are accepted but not used
+	Schema      string          `json:"$schema"`
+	ID          string          `json:"$id"`
+	Comment     string          `json:"$comment"`
+	Title       string          `json:"title"`
+	Description string          `json:"description"`
+	Default     json.RawMessage `json:"default"`
+	Examples    json.RawMessage `json:"examples"`
+
+	pattern      *regexp.Regexp
+	additional   *jsonSchema
+	noAdditional bool
+	constValue   any
+}
+
+// loadJSONSchema reads and compiles the schema in file.
+func loadJSONSchema(file string) (*jsonSchema, error) {
+	buf, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema: %w", err)
+	}
+	var schema jsonSchema
+	if err := decodeJSONSchema(buf, &schema); err != nil {
+		return nil, fmt.Errorf("failed to parse schema %s: %w", file, err)
+	}
+	if err := schema.compile(); err != nil {
+		return nil, fmt.Errorf("invalid schema %s: %w", file, err)
+	}
+	return &schema, nil
+}
+
+// decodeJSONSchema decodes a schema and fails on unsupported keywords, so
+// that a schema is never reported as satisfied without being enforced.
+func decodeJSONSchema(buf []byte, schema *jsonSchema) error {
+	decoder := json.NewDecoder(bytes.NewReader(buf))
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(schema)
+	if err != nil && strings.HasPrefix(err.Error(), "json: unknown field ") {
+		return fmt.Errorf("unsupported keyword %s", strings.TrimPrefix(err.Error(), "json: unknown field "))
+	}
+	return err
+}
+
+func (s *jsonSchema) compile() error {
+	var err error
+	if s.Pattern != "" {
+		s.pattern, err = regexp.Compile(s.Pattern)
+		if err != nil {
+			return fmt.Errorf("invalid pattern: %w", err)
+		}
+	}
+	if len(s.Const) > 0 {
+		if err := json.Unmarshal(s.Const, &s.constValue); err != nil {
+			return fmt.Errorf("invalid const: %w", err)
+		}
+	}
+	if len(s.AdditionalProperties) > 0 {
+		var allowed bool
+		if json.Unmarshal(s.AdditionalProperties, &allowed) == nil {
+			s.noAdditional = !allowed
+		} else {
+			s.additional = &jsonSchema{}
+			if err := decodeJSONSchema(s.AdditionalProperties, s.additional); err != nil {
+				return fmt.Errorf("additionalProperties: %w", err)
+			}
+			if err := s.additional.compile(); err != nil {
+				return err
+			}
+		}
+	}
+	for name, property := range s.Properties {
+		if err := property.compile(); err != nil {
+			return fmt.Errorf("property %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// validate returns a description of each violation of the schema by value,
+// which is located at where, e.g. "owner".
+func (s *jsonSchema) validate(value any, where string) []string {
+	at := func(format string, args ...any) string {
+		if where == "" {
+			return fmt.Sprintf(format, args...)
+		}
+		return where + ": " + fmt.Sprintf(format, args...)
+	}
+	if !s.matchesType(value) {
+		return []string{at("is %s instead of %v", describeValue(value), s.Type)}
+	}
+	problems := make([]string, 0)
+	if len(s.Enum) > 0 && !slices.ContainsFunc(s.Enum, func(allowed any) bool { return jsonEqual(allowed, value) }) {
+		problems = append(problems, at("%v is not one of %v", value, s.Enum))
+	}
+	if len(s.Const) > 0 && !jsonEqual(s.constValue, value) {
+		problems = append(problems, at("%v is not %v", value, s.constValue))
+	}
+	if str, ok := value.(string); ok {
+		length := utf8.RuneCountInString(str)
+		if s.MinLength != nil && length < *s.MinLength {
+			problems = append(problems, at("is shorter than %d characters", *s.MinLength))
+		}
+		if s.MaxLength != nil && length > *s.MaxLength {
+			problems = append(problems, at("is longer than %d characters", *s.MaxLength))
+		}
+		if s.pattern != nil && !s.pattern.MatchString(str) {
+			problems = append(problems, at("%q does not match %s", str, s.Pattern))
+		}
+	}
+	object, ok := value.(map[string]any)
+	if !ok {
+		return problems
+	}
+	for _, key := range s.Required {
+		if _, ok := object[key]; !ok {
+			problems = append(problems, at("missing required key %s", key))
+		}
+	}
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		child := key
+		if where != "" {
+			child = where + "." + key
+		}
+		switch property, ok := s.Properties[key]; {
+		case ok:
+			problems = append(problems, property.validate(object[key], child)...)
+		case s.noAdditional:
+			problems = append(problems, at("unexpected key %s", key))
+		case s.additional != nil:
+			problems = append(problems, s.additional.validate(object[key], child)...)
+		}
+	}
+	return problems
+}
+
+// matchesType checks the type keyword, which is either a type name or a list of them.
+func (s *jsonSchema) matchesType(value any) bool {
+	switch t := s.Type.(type) {
+	case string:
+		return isJSONType(value, t)
+	case []any:
+		return slices.ContainsFunc(t, func(name any) bool {
+			str, ok := name.(string)
+			return ok && isJSONType(value, str)
+		})
+	default:
+		return true
+	}
+}
+
+func isJSONType(value any, name string) bool {
+	switch v := value.(type) {
+	case nil:
+		return name == "null"
+	case bool:
+		return name == "boolean"
+	case string:
+		return name == "string"
+	case float64:
+		return name == "number" || (name == "integer" && v == float64(int64(v)))
+	case int:
+		return name == "number" || name == "integer"
+	case map[string]any:
+		return name == "object"
+	case []any:
+		return name == "array"
+	default:
+		return false
+	}
+}
+
+// jsonEqual compares two decoded JSON values, treating integers like the
+// float64 numbers they decode to.
+func jsonEqual(a, b any) bool {
+	if ai, ok := a.(int); ok {
+		a = float64(ai)
+	}
+	if bi, ok := b.(int); ok {
+		b = float64(bi)
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// validateAgainstSchema checks the custom metadata which update writes for
+// all entries when replacing the current custom metadata, and returns one
+// error listing every violation with its path.
+func validateAgainstSchema(schema *jsonSchema, entries []customMetaEntry, update customMetaUpdate) error {
+	errs := make([]error, 0)
+	for _, entry := range entries {
+		for _, problem := range schema.validate(update.apply(nil, entry.customMeta), "") {
+			errs = append(errs, fmt.Errorf("%s: %s", entry.path, problem))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,116 @@
+/******************************************************************************
+*
+*  Copyright 2024 SAP SE
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func compileTestSchema(t *testing.T, schema string) *jsonSchema {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), "schema.json")
+	if err := os.WriteFile(file, []byte(schema), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	compiled, err := loadJSONSchema(file)
+	if err != nil {
+		t.Fatalf("loadJSONSchema(%s) failed: %s", schema, err)
+	}
+	return compiled
+}
+
+func TestLoadJSONSchemaRejectsUnsupportedKeywords(t *testing.T) {
+	cases := map[string]bool{
+		`{"type": "object"}`: true,
+		`{"$schema": "https://json-schema.org/draft/2020-12/schema", "title": "t", "description": "d", "type": "object"}`: true,
+		`{"properties": {"a": {"type": "string", "maxLength": 3}}}`:                                                       true,
+		`{"additionalProperties": {"type": "string"}}`:                                                                    true,
+		`{"additionalProperties": false}`:                                                                                 true,
+		`{"properties": {"a": {"type": "number", "minimum": 1}}}`:                                                         false,
+		`{"properties": {"a": {"type": "string", "format": "email"}}}`:                                                    false,
+		`{"additionalProperties": {"items": {}}}`:                                                                         false,
+		`{"allOf": [{"type": "object"}]}`:                                                                                 false,
+		`{"$ref": "#/definitions/a"}`:                                                                                     false,
+		`{"pattern": "("}`:                                                                                                false,
+	}
+	for schema, valid := range cases {
+		file := filepath.Join(t.TempDir(), "schema.json")
+		if err := os.WriteFile(file, []byte(schema), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		_, err := loadJSONSchema(file)
+		if valid && err != nil {
+			t.Errorf("loadJSONSchema(%s) failed: %s", schema, err)
+		}
+		if !valid && err == nil {
+			t.Errorf("loadJSONSchema(%s) succeeded, expected an error", schema)
+		}
+	}
+}
+
+func TestJSONSchemaValidate(t *testing.T) {
+	schema := compileTestSchema(t, `{
+		"type": "object",
+		"required": ["owner"],
+		"additionalProperties": false,
+		"properties": {
+			"owner": {"type": "string", "minLength": 2, "maxLength": 5, "pattern": "^[a-z]+$"},
+			"env": {"enum": ["prod", "dev"]},
+			"kind": {"const": "secret"},
+			"count": {"type": ["integer", "null"]}
+		}
+	}`)
+	cases := []struct {
+		value    map[string]any
+		problems int
+	}{
+		{map[string]any{"owner": "team"}, 0},
+		{map[string]any{"owner": "team", "env": "dev", "kind": "secret", "count": float64(3)}, 0},
+		{map[string]any{"owner": "team", "count": nil}, 0},
+		{map[string]any{}, 1},
+		{map[string]any{"owner": "t"}, 1},
+		{map[string]any{"owner": "toolong"}, 1},
+		{map[string]any{"owner": "Team"}, 1},
+		{map[string]any{"owner": float64(1)}, 1},
+		{map[string]any{"owner": "team", "env": "qa"}, 1},
+		{map[string]any{"owner": "team", "kind": "other"}, 1},
+		{map[string]any{"owner": "team", "count": 1.5}, 1},
+		{map[string]any{"owner": "team", "onwer": "team"}, 1},
+		{map[string]any{"onwer": "team"}, 2},
+	}
+	for _, c := range cases {
+		if problems := schema.validate(c.value, ""); len(problems) != c.problems {
+			t.Errorf("validate(%v) = %q, expected %d problem(s)", c.value, problems, c.problems)
+		}
+	}
+}
+
+func TestValidateAgainstSchemaUsesResult(t *testing.T) {
+	schema := compileTestSchema(t, `{"type": "object", "required": ["owner"]}`)
+	entries := []customMetaEntry{{path: "a", customMeta: map[string]any{"owner": "team", "env": "dev"}}}
+	if err := validateAgainstSchema(schema, entries, customMetaUpdate{}); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	// the required key is removed from what would be written
+	if err := validateAgainstSchema(schema, entries, customMetaUpdate{deleteKeys: []string{"owner"}}); err == nil {
+		t.Error("expected an error when --delete-key removes a required key")
+	}
+}
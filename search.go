@@ -0,0 +1,114 @@
+/******************************************************************************
+*
+*  Copyright 2024 SAP SE
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sync"
+
+	"github.com/urfave/cli/v2"
+)
+
+func search(ctx *cli.Context) (err error) {
+	key := ctx.String("key")
+	var pattern *regexp.Regexp
+	if ctx.Bool("regex") {
+		if !ctx.IsSet("value") {
+			return errors.New("--regex requires --value")
+		}
+		var err error
+		pattern, err = regexp.Compile(ctx.String("value"))
+		if err != nil {
+			return fmt.Errorf("invalid regular expression: %w", err)
+		}
+	}
+	matches := func(value any) bool {
+		switch {
+		case pattern != nil:
+			return pattern.MatchString(fmt.Sprint(value))
+		case ctx.IsSet("value"):
+			return fmt.Sprint(value) == ctx.String("value")
+		default:
+			return true
+		}
+	}
+
+	client, err := createClient(ctx)
+	if err != nil {
+		return err
+	}
+	out, err := openOutput(ctx)
+	if err != nil {
+		return err
+	}
+	defer closeOutput(out, &err)
+	mount := ctx.String("mount")
+	lister := newLister(ctx, client)
+	paths, err := lister.listSecretDirRecurse(ctx.Context, "/")
+	if err != nil {
+		return err
+	}
+
+	result := make([]Result[string], 0)
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	for _, path := range paths {
+		path = relativePath(path)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := lister.sema.Acquire(ctx.Context, 1); err != nil {
+				mutex.Lock()
+				defer mutex.Unlock()
+				result = append(result, Result[string]{err: err})
+				return
+			}
+			meta, err := client.KVv2(mount).GetMetadata(ctx.Context, path)
+			lister.sema.Release(1)
+			mutex.Lock()
+			defer mutex.Unlock()
+			if err != nil {
+				result = append(result, Result[string]{err: fmt.Errorf("failed to get metadata for %s: %w", path, err)})
+				return
+			}
+			if value, ok := meta.CustomMetadata[key]; ok && matches(value) {
+				result = append(result, Result[string]{value: path})
+			}
+		}()
+	}
+
+	wg.Wait()
+	errs := make([]error, 0)
+	for _, r := range result {
+		if r.err != nil {
+			errs = append(errs, r.err)
+			continue
+		}
+		fmt.Fprintln(out, r.value)
+	}
+	if len(errs) > 0 {
+		return joinErrors(errs, len(paths)-len(errs))
+	}
+	if len(result) == 0 {
+		return errNoResults
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+/******************************************************************************
+*
+*  Copyright 2024 SAP SE
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/urfave/cli/v2"
+)
+
+// staleSecret is a secret whose latest version is older than the threshold
+// of kv stale.
+type staleSecret struct {
+	Path        string    `json:"path"            yaml:"path"`
+	Version     int       `json:"version"         yaml:"version"`
+	CreatedTime time.Time `json:"created_time"    yaml:"created_time"`
+	Owner       string    `json:"owner,omitempty" yaml:"owner,omitempty"`
+}
+
+func stale(ctx *cli.Context) (err error) {
+	format := ctx.String("format")
+	if err := checkFormat(format, "table", "json", "yaml"); err != nil {
+		return err
+	}
+	olderThan, err := parseDuration(ctx.String("older-than"))
+	if err != nil {
+		return fmt.Errorf("invalid --older-than: %w", err)
+	}
+	if olderThan <= 0 {
+		return errors.New("--older-than must be positive")
+	}
+	client, err := createClient(ctx)
+	if err != nil {
+		return err
+	}
+	out, err := openOutput(ctx)
+	if err != nil {
+		return err
+	}
+	defer closeOutput(out, &err)
+	kv := client.KVv2(ctx.String("mount"))
+	lister := newLister(ctx, client)
+	paths, err := lister.listSecretDirRecurse(ctx.Context, "/")
+	if err != nil {
+		return err
+	}
+	sort.Strings(paths)
+
+	cutoff := time.Now().Add(-olderThan)
+	ownerKey := ctx.String("owner-key")
+	// secrets which are not stale have a zero version
+	result := mapConcurrently(ctx.Context, paths, func(path string) (staleSecret, error) {
+		path = relativePath(path)
+		meta, err := kv.GetMetadata(ctx.Context, path)
+		if err != nil {
+			return staleSecret{}, fmt.Errorf("failed to get metadata for %s: %w", path, err)
+		}
+		latest, ok := meta.Versions[strconv.Itoa(meta.CurrentVersion)]
+		// deleted and destroyed secrets are no longer in use and need no rotation
+		if !ok || latest.Destroyed || isDeleted(latest) || !latest.CreatedTime.Before(cutoff) {
+			return staleSecret{}, nil
+		}
+		secret := staleSecret{Path: path, Version: meta.CurrentVersion, CreatedTime: latest.CreatedTime}
+		if owner, ok := meta.CustomMetadata[ownerKey]; ok {
+			secret.Owner = fmt.Sprint(owner)
+		}
+		return secret, nil
+	})
+
+	found := make([]staleSecret, 0)
+	errs := make([]error, 0)
+	for _, r := range result {
+		switch {
+		case r.err != nil:
+			errs = append(errs, r.err)
+		case r.value.Version > 0:
+			found = append(found, r.value)
+		}
+	}
+	if format != "table" {
+		err = encodeOutput(out, format, found)
+	} else {
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "PATH\tVERSION\tCREATED\tAGE\tOWNER")
+		for _, s := range found {
+			owner := s.Owner
+			if owner == "" {
+				owner = "-"
+			}
+			age := time.Since(s.CreatedTime) / (24 * time.Hour)
+			fmt.Fprintf(w, "%s\t%d\t%s\t%dd\t%s\n", s.Path, s.Version, s.CreatedTime.Format(time.RFC3339), age, owner)
+		}
+		err = w.Flush()
+	}
+	if err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		return joinErrors(errs, len(paths)-len(errs))
+	}
+	if len(found) == 0 {
+		return errNoResults
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+/******************************************************************************
+*
+*  Copyright 2024 SAP SE
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/urfave/cli/v2"
+)
+
+// secretStat is the summary of a secret as printed by kv stat.
+type secretStat struct {
+	Path               string `json:"path"                 yaml:"path"`
+	CurrentVersion     int    `json:"current_version"      yaml:"current_version"`
+	Versions           int    `json:"versions"             yaml:"versions"`
+	Deleted            int    `json:"deleted"              yaml:"deleted"`
+	Destroyed          int    `json:"destroyed"            yaml:"destroyed"`
+	CASRequired        bool   `json:"cas_required"         yaml:"cas_required"`
+	MaxVersions        int    `json:"max_versions"         yaml:"max_versions"`
+	CustomMetadataKeys int    `json:"custom_metadata_keys" yaml:"custom_metadata_keys"`
+}
+
+func stat(ctx *cli.Context) (err error) {
+	format := ctx.String("format")
+	if err := checkFormat(format, "table", "json", "yaml"); err != nil {
+		return err
+	}
+	client, err := createClient(ctx)
+	if err != nil {
+		return err
+	}
+	out, err := openOutput(ctx)
+	if err != nil {
+		return err
+	}
+	defer closeOutput(out, &err)
+	kv := client.KVv2(ctx.String("mount"))
+	result := mapConcurrently(ctx.Context, pathArgs(ctx), func(path string) (secretStat, error) {
+		meta, err := kv.GetMetadata(ctx.Context, path)
+		if err != nil {
+			return secretStat{}, fmt.Errorf("failed to get metadata for %s: %w", path, err)
+		}
+		summary := secretStat{
+			Path:               path,
+			CurrentVersion:     meta.CurrentVersion,
+			Versions:           len(meta.Versions),
+			CASRequired:        meta.CASRequired,
+			MaxVersions:        meta.MaxVersions,
+			CustomMetadataKeys: len(meta.CustomMetadata),
+		}
+		for _, v := range meta.Versions {
+			switch {
+			case v.Destroyed:
+				summary.Destroyed++
+			case isDeleted(v):
+				summary.Deleted++
+			}
+		}
+		return summary, nil
+	})
+
+	summaries := make([]secretStat, 0, len(result))
+	errs := make([]error, 0)
+	for _, r := range result {
+		if r.err != nil {
+			errs = append(errs, r.err)
+			continue
+		}
+		summaries = append(summaries, r.value)
+	}
+	if err := printStatSummaries(out, format, summaries); err != nil {
+		return err
+	}
+	return joinErrors(errs, len(summaries))
+}
+
+// printStatSummaries writes the summaries of the secrets in the given format.
+func printStatSummaries(out io.Writer, format string, summaries []secretStat) error {
+	if format != "table" {
+		return encodeOutput(out, format, summaries)
+	}
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PATH\tCURRENT\tVERSIONS\tDELETED\tDESTROYED\tCAS REQUIRED\tMAX VERSIONS\tCUSTOM KEYS")
+	for _, s := range summaries {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\t%t\t%d\t%d\n", s.Path, s.CurrentVersion, s.Versions, s.Deleted, s.Destroyed, s.CASRequired, s.MaxVersions, s.CustomMetadataKeys)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,72 @@
+/******************************************************************************
+*
+*  Copyright 2024 SAP SE
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/urfave/cli/v2"
+)
+
+// stats collects the counters printed by --stats.
+var stats = requestStats{start: time.Now()}
+
+type requestStats struct {
+	start    time.Time
+	requests atomic.Int64
+	// indexed by the first digit of the status code
+	byClass          [6]atomic.Int64
+	failed           atomic.Int64
+	forbiddenSkipped atomic.Int64
+}
+
+// statsTransport counts every request sent to vault.
+type statsTransport struct {
+	next http.RoundTripper
+}
+
+func (t statsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	stats.requests.Add(1)
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		stats.failed.Add(1)
+		return resp, err
+	}
+	if class := resp.StatusCode / 100; class >= 0 && class < len(stats.byClass) {
+		stats.byClass[class].Add(1)
+	}
+	return resp, nil
+}
+
+func printStats(ctx *cli.Context) error {
+	if !ctx.Bool("stats") {
+		return nil
+	}
+	elapsed := time.Since(stats.start)
+	requests := stats.requests.Load()
+	fmt.Fprintf(os.Stderr, "requests: %d (2xx: %d, 4xx: %d, 5xx: %d, failed: %d)\n",
+		requests, stats.byClass[2].Load(), stats.byClass[4].Load(), stats.byClass[5].Load(), stats.failed.Load())
+	fmt.Fprintf(os.Stderr, "forbidden paths skipped: %d\n", stats.forbiddenSkipped.Load())
+	fmt.Fprintf(os.Stderr, "duration: %s (%.1f requests/s)\n", elapsed.Round(time.Millisecond), float64(requests)/elapsed.Seconds())
+	return nil
+}
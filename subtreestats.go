@@ -0,0 +1,146 @@
+/******************************************************************************
+*
+*  Copyright 2024 SAP SE
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/urfave/cli/v2"
+)
+
+// subtreeStats aggregates the secrets below a directory as printed by kv stats.
+type subtreeStats struct {
+	Prefix   string `json:"prefix"   yaml:"prefix"`
+	Secrets  int    `json:"secrets"  yaml:"secrets"`
+	Versions int    `json:"versions" yaml:"versions"`
+	// number of secrets whose current version is soft-deleted
+	DeletedLatest int       `json:"deleted_latest" yaml:"deleted_latest"`
+	OldestUpdate  time.Time `json:"oldest_update"  yaml:"oldest_update"`
+	NewestUpdate  time.Time `json:"newest_update"  yaml:"newest_update"`
+}
+
+// statsPrefix returns the directory of path cut to at most depth segments,
+// e.g. "team/app/" for "team/app/db/password" and depth 2.
+func statsPrefix(path string, depth int) string {
+	segments := strings.Split(path, "/")
+	dirs := segments[:len(segments)-1]
+	if len(dirs) > depth {
+		dirs = dirs[:depth]
+	}
+	if len(dirs) == 0 {
+		return "/"
+	}
+	return strings.Join(dirs, "/") + "/"
+}
+
+func kvStats(ctx *cli.Context) (err error) {
+	format := ctx.String("format")
+	if err := checkFormat(format, "table", "json", "yaml"); err != nil {
+		return err
+	}
+	depth := ctx.Int("depth")
+	if depth < 0 {
+		return errors.New("--depth must not be negative")
+	}
+	client, err := createClient(ctx)
+	if err != nil {
+		return err
+	}
+	out, err := openOutput(ctx)
+	if err != nil {
+		return err
+	}
+	defer closeOutput(out, &err)
+	kv := client.KVv2(ctx.String("mount"))
+	lister := newLister(ctx, client)
+	paths, err := lister.listSecretDirRecurse(ctx.Context, "/")
+	if err != nil {
+		return err
+	}
+
+	result := mapConcurrently(ctx.Context, paths, func(path string) (subtreeStats, error) {
+		path = relativePath(path)
+		meta, err := kv.GetMetadata(ctx.Context, path)
+		if err != nil {
+			return subtreeStats{}, fmt.Errorf("failed to get metadata for %s: %w", path, err)
+		}
+		summary := subtreeStats{
+			Prefix:       statsPrefix(path, depth),
+			Secrets:      1,
+			Versions:     len(meta.Versions),
+			OldestUpdate: meta.UpdatedTime,
+			NewestUpdate: meta.UpdatedTime,
+		}
+		current := meta.Versions[strconv.Itoa(meta.CurrentVersion)]
+		if isDeleted(current) && !current.Destroyed {
+			summary.DeletedLatest = 1
+		}
+		return summary, nil
+	})
+
+	byPrefix := make(map[string]*subtreeStats)
+	errs := make([]error, 0)
+	for _, r := range result {
+		if r.err != nil {
+			errs = append(errs, r.err)
+			continue
+		}
+		s, ok := byPrefix[r.value.Prefix]
+		if !ok {
+			value := r.value
+			byPrefix[r.value.Prefix] = &value
+			continue
+		}
+		s.Secrets++
+		s.Versions += r.value.Versions
+		s.DeletedLatest += r.value.DeletedLatest
+		if r.value.OldestUpdate.Before(s.OldestUpdate) {
+			s.OldestUpdate = r.value.OldestUpdate
+		}
+		if r.value.NewestUpdate.After(s.NewestUpdate) {
+			s.NewestUpdate = r.value.NewestUpdate
+		}
+	}
+	aggregated := make([]subtreeStats, 0, len(byPrefix))
+	for _, s := range byPrefix {
+		aggregated = append(aggregated, *s)
+	}
+	sort.Slice(aggregated, func(i, j int) bool { return aggregated[i].Prefix < aggregated[j].Prefix })
+
+	if format != "table" {
+		err = encodeOutput(out, format, aggregated)
+	} else {
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "PREFIX\tSECRETS\tVERSIONS\tDELETED LATEST\tOLDEST UPDATE\tNEWEST UPDATE")
+		for _, s := range aggregated {
+			fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%s\t%s\n", s.Prefix, s.Secrets, s.Versions, s.DeletedLatest, s.OldestUpdate.Format(time.RFC3339), s.NewestUpdate.Format(time.RFC3339))
+		}
+		err = w.Flush()
+	}
+	if err != nil {
+		return err
+	}
+	return joinErrors(errs, len(paths)-len(errs))
+}
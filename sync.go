@@ -0,0 +1,177 @@
+/******************************************************************************
+*
+*  Copyright 2024 SAP SE
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/urfave/cli/v2"
+)
+
+// syncFlags are the flags of sync and mirror selecting the destination.
+func syncFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:    "dst-addr",
+			Usage:   "Address of the destination vault, defaults to the source vault",
+			EnvVars: []string{"MUTAVAULT_DST_ADDR"},
+		},
+		&cli.StringFlag{
+			Name:    "dst-token",
+			Usage:   "Token for the destination vault, required with --dst-addr",
+			EnvVars: []string{"MUTAVAULT_DST_TOKEN"},
+		},
+		&cli.StringFlag{
+			Name:   "dst-mount",
+			Usage:  "Mount path of the destination kvv2 engine, defaults to --mount",
+			Action: normalizeMountFlag("dst-mount"),
+		},
+	}
+}
+
+func syncSecrets(ctx *cli.Context) error {
+	client, err := createClient(ctx)
+	if err != nil {
+		return err
+	}
+	dstClient, err := createDestinationClient(ctx, client)
+	if err != nil {
+		return err
+	}
+	dstMount := ctx.String("dst-mount")
+	if dstMount == "" {
+		dstMount = ctx.String("mount")
+	}
+	dirs := pathArgs(ctx)
+	if len(dirs) == 0 {
+		dirs = []string{""}
+	}
+	paths, err := listRecursive(ctx, client, dirs)
+	if err != nil {
+		return err
+	}
+	sort.Strings(paths)
+
+	dryRun := ctx.Bool("dry-run")
+	srcKV, dstKV := client.KVv2(ctx.String("mount")), dstClient.KVv2(dstMount)
+	result := mapConcurrently(ctx.Context, paths, func(path string) (string, error) {
+		return syncSecret(ctx.Context, srcKV, dstKV, path, dryRun)
+	})
+	errs := make([]error, 0)
+	for idx, r := range result {
+		if r.err != nil {
+			errs = append(errs, r.err)
+			continue
+		}
+		if r.value != "" {
+			fmt.Printf("%s: %s\n", paths[idx], r.value)
+		}
+	}
+	return joinErrors(errs, len(result)-len(errs))
+}
+
+// createDestinationClient returns a client for the Vault given by --dst-addr
+// or the source client if it is not set.
+func createDestinationClient(ctx *cli.Context, client *api.Client) (*api.Client, error) {
+	addr, token := ctx.String("dst-addr"), ctx.String("dst-token")
+	if addr == "" {
+		if token != "" {
+			return nil, errors.New("--dst-token is only used together with --dst-addr")
+		}
+		return client, nil
+	}
+	// the source token must never be sent to another vault
+	if token == "" {
+		return nil, errors.New("--dst-addr requires --dst-token or MUTAVAULT_DST_TOKEN")
+	}
+	dstClient, err := reconfigureClient(client, func(config *api.Config) error {
+		config.Address = addr
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	dstClient.SetToken(token)
+	return dstClient, nil
+}
+
+// syncSecret copies the latest data and the custom metadata of a secret if
+// they differ from the destination and describes what was changed.
+func syncSecret(ctx context.Context, srcKV, dstKV *api.KVv2, path string, dryRun bool) (string, error) {
+	src, err := srcKV.Get(ctx, path)
+	if errors.Is(err, api.ErrSecretNotFound) || (err == nil && src.Data == nil) {
+		// nothing to sync if the latest version is deleted, which vault returns
+		// with its metadata but without data
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret %s: %w", path, err)
+	}
+	dst, err := dstKV.Get(ctx, path)
+	switch {
+	case errors.Is(err, api.ErrSecretNotFound):
+		dst = &api.KVSecret{}
+	case err != nil:
+		return "", fmt.Errorf("failed to read destination secret %s: %w", path, err)
+	}
+
+	// a deleted destination is missing its data, but keeps its custom metadata
+	dataChanged := dst.Data == nil || !reflect.DeepEqual(src.Data, dst.Data)
+	metaChanged := !diffMetadata(src.CustomMetadata, dst.CustomMetadata).isEmpty()
+	var change string
+	switch {
+	case dataChanged && metaChanged:
+		change = "data and custom metadata"
+	case dataChanged:
+		change = "data"
+	case metaChanged:
+		change = "custom metadata"
+	default:
+		return "", nil
+	}
+	if dryRun {
+		return "would sync " + change, nil
+	}
+	if dataChanged {
+		if _, err := dstKV.Put(ctx, path, src.Data); err != nil {
+			return "", fmt.Errorf("failed to write destination secret %s: %w", path, err)
+		}
+	}
+	if metaChanged {
+		// patching keeps the settings of the destination, but merges the
+		// custom metadata, so keys missing in the source have to be nulled
+		customMeta := make(map[string]any, len(src.CustomMetadata))
+		for key := range dst.CustomMetadata {
+			customMeta[key] = nil
+		}
+		for key, value := range src.CustomMetadata {
+			customMeta[key] = value
+		}
+		err := dstKV.PatchMetadata(ctx, path, api.KVMetadataPatchInput{CustomMetadata: customMeta})
+		if err != nil {
+			return "", fmt.Errorf("failed to update metadata for destination secret %s: %w", path, err)
+		}
+	}
+	return "synced " + change, nil
+}
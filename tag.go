@@ -0,0 +1,65 @@
+/******************************************************************************
+*
+*  Copyright 2024 SAP SE
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/urfave/cli/v2"
+)
+
+func tag(ctx *cli.Context) error {
+	key, value := ctx.String("key"), ctx.String("value")
+	client, err := createClient(ctx)
+	if err != nil {
+		return err
+	}
+	paths := pathArgs(ctx)
+	if ctx.Bool("recursive") {
+		paths, err = listRecursive(ctx, client, paths)
+		if err != nil {
+			return err
+		}
+	}
+	kv := client.KVv2(ctx.String("mount"))
+	result := mapConcurrently(ctx.Context, paths, func(path string) (bool, error) {
+		changed := false
+		err := modifyCustomMeta(ctx.Context, kv, path, func(customMeta map[string]any) bool {
+			if current, ok := customMeta[key]; ok && current == value {
+				return false
+			}
+			customMeta[key] = value
+			changed = true
+			return true
+		})
+		return changed, err
+	})
+
+	errs := make([]error, 0)
+	for idx, r := range result {
+		if r.err != nil {
+			errs = append(errs, r.err)
+			continue
+		}
+		if r.value {
+			fmt.Printf("%s: set %s=%s\n", paths[idx], key, value)
+		}
+	}
+	return joinErrors(errs, len(result)-len(errs))
+}
@@ -0,0 +1,115 @@
+/******************************************************************************
+*
+*  Copyright 2024 SAP SE
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/urfave/cli/v2"
+)
+
+// metaTemplateData is passed to the templates of setcustommetas --template.
+type metaTemplateData struct {
+	// the path of the secret, e.g. "teams/x/prod/db"
+	Path string
+	// the segments of the path, e.g. ["teams", "x", "prod", "db"]
+	PathParts []string
+}
+
+// metaTemplate is a custom metadata key with the template of its value.
+type metaTemplate struct {
+	key      string
+	template *template.Template
+}
+
+// parseMetaTemplates parses "key=template,key=template". Commas inside of
+// template actions do not separate keys.
+func parseMetaTemplates(input string) ([]metaTemplate, error) {
+	parts := make([]string, 0)
+	depth, start := 0, 0
+	for idx := 0; idx < len(input); idx++ {
+		switch {
+		case strings.HasPrefix(input[idx:], "{{"):
+			depth++
+			idx++
+		case strings.HasPrefix(input[idx:], "}}") && depth > 0:
+			depth--
+			idx++
+		case input[idx] == ',' && depth == 0:
+			parts = append(parts, input[start:idx])
+			start = idx + 1
+		}
+	}
+	parts = append(parts, input[start:])
+
+	templates := make([]metaTemplate, 0, len(parts))
+	for _, part := range parts {
+		key, text, ok := strings.Cut(part, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("expected key=template instead of %q", part)
+		}
+		tmpl, err := template.New(key).Option("missingkey=error").Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid template for %s: %w", key, err)
+		}
+		templates = append(templates, metaTemplate{key: key, template: tmpl})
+	}
+	return templates, nil
+}
+
+// templateCustomMetaEntries renders the templates for every secret matching
+// the --match glob. All paths are rendered before anything is written, so
+// that an error in any of them rejects the whole batch.
+func templateCustomMetaEntries(ctx *cli.Context, client *api.Client) ([]customMetaEntry, error) {
+	if !ctx.IsSet("match") {
+		return nil, errors.New("--template requires --match to select the secrets")
+	}
+	templates, err := parseMetaTemplates(ctx.String("template"))
+	if err != nil {
+		return nil, err
+	}
+	paths, err := expandGlob(ctx, client, cleanPath(ctx.String("match")))
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]customMetaEntry, 0, len(paths))
+	errs := make([]error, 0)
+	for _, path := range paths {
+		data := metaTemplateData{Path: path, PathParts: strings.Split(path, "/")}
+		customMeta := make(map[string]any, len(templates))
+		for _, t := range templates {
+			var value strings.Builder
+			if err := t.template.Execute(&value, data); err != nil {
+				errs = append(errs, fmt.Errorf("failed to render %s for %s: %w", t.key, path, err))
+				continue
+			}
+			customMeta[t.key] = value.String()
+		}
+		entries = append(entries, customMetaEntry{path: path, customMeta: customMeta})
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return entries, nil
+}
@@ -0,0 +1,132 @@
+/******************************************************************************
+*
+*  Copyright 2024 SAP SE
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+)
+
+// treeNode is a directory or secret in the hierarchy of a kv engine.
+type treeNode struct {
+	name     string
+	children map[string]*treeNode
+}
+
+func tree(ctx *cli.Context) (err error) {
+	if err := checkForbiddenMode(ctx); err != nil {
+		return err
+	}
+	client, err := createClient(ctx)
+	if err != nil {
+		return err
+	}
+	out, err := openOutput(ctx)
+	if err != nil {
+		return err
+	}
+	defer closeOutput(out, &err)
+	lister := newLister(ctx, client)
+	paths, err := lister.listSecretDirRecurse(ctx.Context, "/")
+	if err != nil {
+		return err
+	}
+	root := buildTree(paths)
+	fmt.Fprintln(out, ".")
+	printTree(out, root, "", false)
+	return lister.reportForbidden()
+}
+
+// printMountTree prints the paths found in mount as a tree followed by a
+// summary like tree(1).
+func printMountTree(w io.Writer, mount string, paths []string, withMount bool) {
+	root := buildTree(paths)
+	label := "."
+	if withMount {
+		label = mount
+	}
+	fmt.Fprintln(w, label)
+	printTree(w, root, "", true)
+	dirs, secrets := root.count()
+	fmt.Fprintf(w, "\n%d directories, %d secrets\n", dirs, secrets)
+}
+
+// buildTree turns a flat list of paths as returned by listSecretDirRecurse
+// into a hierarchy. Directory names keep their trailing slash.
+func buildTree(paths []string) *treeNode {
+	root := &treeNode{children: make(map[string]*treeNode)}
+	for _, path := range paths {
+		node := root
+		segments := strings.SplitAfter(strings.TrimPrefix(path, "/"), "/")
+		for _, segment := range segments {
+			if segment == "" {
+				continue
+			}
+			child, ok := node.children[segment]
+			if !ok {
+				child = &treeNode{name: segment, children: make(map[string]*treeNode)}
+				node.children[segment] = child
+			}
+			node = child
+		}
+	}
+	return root
+}
+
+// count returns the number of directories and secrets below node.
+func (n *treeNode) count() (dirs, secrets int) {
+	for name, child := range n.children {
+		if !strings.HasSuffix(name, "/") {
+			secrets++
+			continue
+		}
+		childDirs, childSecrets := child.count()
+		dirs += childDirs + 1
+		secrets += childSecrets
+	}
+	return dirs, secrets
+}
+
+// printTree prints the children of node, withCounts adds the number of
+// secrets below each directory.
+func printTree(w io.Writer, node *treeNode, indent string, withCounts bool) {
+	names := make([]string, 0, len(node.children))
+	for name := range node.children {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for idx, name := range names {
+		branch, nextIndent := "├── ", "│   "
+		if idx == len(names)-1 {
+			branch, nextIndent = "└── ", "    "
+		}
+		child := node.children[name]
+		if withCounts && strings.HasSuffix(name, "/") {
+			_, secrets := child.count()
+			fmt.Fprintf(w, "%s%s%s (%d)\n", indent, branch, name, secrets)
+		} else {
+			fmt.Fprintf(w, "%s%s%s\n", indent, branch, name)
+		}
+		printTree(w, child, indent+nextIndent, withCounts)
+	}
+}
@@ -0,0 +1,126 @@
+/******************************************************************************
+*
+*  Copyright 2024 SAP SE
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/urfave/cli/v2"
+)
+
+// verifyResult is the outcome of kv verify for a single path.
+type verifyResult struct {
+	Path string `json:"path"                   yaml:"path"`
+	// "ok", "missing", "forbidden", "missing-keys" or "error"
+	Status      string   `json:"status"                 yaml:"status"`
+	MissingKeys []string `json:"missing_keys,omitempty" yaml:"missing_keys,omitempty"`
+	Error       string   `json:"error,omitempty"        yaml:"error,omitempty"`
+}
+
+func verify(ctx *cli.Context) (err error) {
+	format := ctx.String("format")
+	if err := checkFormat(format, "text", "json", "yaml"); err != nil {
+		return err
+	}
+	client, err := createClient(ctx)
+	if err != nil {
+		return err
+	}
+	out, err := openOutput(ctx)
+	if err != nil {
+		return err
+	}
+	defer closeOutput(out, &err)
+	paths, err := readPaths(ctx)
+	if err != nil {
+		return err
+	}
+	if len(paths) == 0 {
+		return errors.New("no paths were given")
+	}
+	requiredKeys := ctx.StringSlice("require-key")
+	mount, kvVersion := ctx.String("mount"), ctx.Int("kv-version")
+
+	result := mapConcurrently(ctx.Context, paths, func(path string) (verifyResult, error) {
+		var secret *api.KVSecret
+		var err error
+		if kvVersion == 1 {
+			secret, err = client.KVv1(mount).Get(ctx.Context, path)
+		} else {
+			secret, err = client.KVv2(mount).Get(ctx.Context, path)
+		}
+		var respErr *api.ResponseError
+		switch {
+		case errors.Is(err, api.ErrSecretNotFound) || (err == nil && secret.Data == nil):
+			return verifyResult{Path: path, Status: "missing"}, nil
+		case errors.As(err, &respErr) && respErr.StatusCode == http.StatusForbidden:
+			return verifyResult{Path: path, Status: "forbidden"}, nil
+		case err != nil:
+			return verifyResult{Path: path, Status: "error", Error: err.Error()}, nil
+		}
+		missing := make([]string, 0)
+		for _, key := range requiredKeys {
+			if _, ok := secret.Data[key]; !ok {
+				missing = append(missing, key)
+			}
+		}
+		if len(missing) > 0 {
+			return verifyResult{Path: path, Status: "missing-keys", MissingKeys: missing}, nil
+		}
+		return verifyResult{Path: path, Status: "ok"}, nil
+	})
+
+	results := make([]verifyResult, 0, len(result))
+	failed := 0
+	for _, r := range result {
+		if r.err != nil {
+			// only a cancelled context ends up here
+			return r.err
+		}
+		if r.value.Status != "ok" {
+			failed++
+		}
+		results = append(results, r.value)
+	}
+	if format != "text" {
+		err = encodeOutput(out, format, results)
+	} else {
+		for _, r := range results {
+			switch {
+			case len(r.MissingKeys) > 0:
+				fmt.Fprintf(out, "%s: %s %s\n", r.Path, r.Status, strings.Join(r.MissingKeys, ","))
+			case r.Error != "":
+				fmt.Fprintf(out, "%s: %s %s\n", r.Path, r.Status, r.Error)
+			default:
+				fmt.Fprintf(out, "%s: %s\n", r.Path, r.Status)
+			}
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d path(s) failed verification", failed, len(results))
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+/******************************************************************************
+*
+*  Copyright 2024 SAP SE
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/urfave/cli/v2"
+)
+
+// versionInfo describes a single version of a secret.
+type versionInfo struct {
+	Version     int       `json:"version"                 yaml:"version"`
+	CreatedTime time.Time `json:"created_time"            yaml:"created_time"`
+	// in the future for live versions when delete_version_after is set
+	DeletionTime *time.Time `json:"deletion_time,omitempty" yaml:"deletion_time,omitempty"`
+	Deleted      bool       `json:"deleted"                 yaml:"deleted"`
+	Destroyed    bool       `json:"destroyed"               yaml:"destroyed"`
+}
+
+// secretVersions is the version history of a secret as printed by kv versions.
+type secretVersions struct {
+	Path     string        `json:"path"     yaml:"path"`
+	Versions []versionInfo `json:"versions" yaml:"versions"`
+}
+
+func versions(ctx *cli.Context) (err error) {
+	format := ctx.String("format")
+	if err := checkFormat(format, "table", "json", "yaml"); err != nil {
+		return err
+	}
+	client, err := createClient(ctx)
+	if err != nil {
+		return err
+	}
+	out, err := openOutput(ctx)
+	if err != nil {
+		return err
+	}
+	defer closeOutput(out, &err)
+	kv := client.KVv2(ctx.String("mount"))
+	result := mapConcurrently(ctx.Context, pathArgs(ctx), func(path string) (secretVersions, error) {
+		meta, err := kv.GetMetadata(ctx.Context, path)
+		if err != nil {
+			return secretVersions{}, fmt.Errorf("failed to get metadata for %s: %w", path, err)
+		}
+		history := secretVersions{Path: path, Versions: make([]versionInfo, 0, len(meta.Versions))}
+		for _, v := range meta.Versions {
+			info := versionInfo{Version: v.Version, CreatedTime: v.CreatedTime, Deleted: isDeleted(v), Destroyed: v.Destroyed}
+			if !v.DeletionTime.IsZero() {
+				info.DeletionTime = &v.DeletionTime
+			}
+			history.Versions = append(history.Versions, info)
+		}
+		sort.Slice(history.Versions, func(i, j int) bool {
+			return history.Versions[i].Version < history.Versions[j].Version
+		})
+		return history, nil
+	})
+
+	histories := make([]secretVersions, 0, len(result))
+	errs := make([]error, 0)
+	for _, r := range result {
+		if r.err != nil {
+			errs = append(errs, r.err)
+			continue
+		}
+		histories = append(histories, r.value)
+	}
+	if err := printVersions(out, format, histories); err != nil {
+		return err
+	}
+	return joinErrors(errs, len(histories))
+}
+
+// printVersions writes the version histories in the given format.
+func printVersions(out io.Writer, format string, histories []secretVersions) error {
+	if format != "table" {
+		return encodeOutput(out, format, histories)
+	}
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PATH\tVERSION\tCREATED\tDELETED\tDESTROYED")
+	for _, history := range histories {
+		for _, v := range history.Versions {
+			deleted := "-"
+			switch {
+			case v.Deleted:
+				deleted = v.DeletionTime.Format(time.RFC3339)
+			case v.DeletionTime != nil:
+				deleted = "scheduled " + v.DeletionTime.Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%t\n", history.Path, v.Version, v.CreatedTime.Format(time.RFC3339), deleted, v.Destroyed)
+		}
+	}
+	return w.Flush()
+}
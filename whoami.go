@@ -0,0 +1,92 @@
+/******************************************************************************
+*
+*  Copyright 2024 SAP SE
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/urfave/cli/v2"
+)
+
+// tokenInfo is the output of whoami.
+type tokenInfo struct {
+	Accessor    string `json:"accessor"`
+	DisplayName string `json:"display_name"`
+	// all policies, including those inherited from the identity
+	Policies         []string `json:"policies"`
+	IdentityPolicies []string `json:"identity_policies"`
+	// remaining lifetime in seconds, 0 for tokens which do not expire
+	TTL        int64  `json:"ttl"`
+	EntityID   string `json:"entity_id"`
+	Namespace  string `json:"namespace"`
+	Renewable  bool   `json:"renewable"`
+	ExpireTime string `json:"expire_time,omitempty"`
+}
+
+func whoami(ctx *cli.Context) error {
+	client, err := createClient(ctx)
+	if err != nil {
+		return err
+	}
+	secret, err := client.Auth().Token().LookupSelfWithContext(ctx.Context)
+	if err != nil {
+		return fmt.Errorf("failed to look up token: %w", err)
+	}
+	if secret == nil || secret.Data == nil {
+		return errors.New("empty response from token lookup")
+	}
+	info := tokenInfo{Policies: []string{}, IdentityPolicies: []string{}}
+	if info.Accessor, err = secret.TokenAccessor(); err != nil {
+		return err
+	}
+	if policies, err := secret.TokenPolicies(); err != nil {
+		return err
+	} else if policies != nil {
+		info.Policies = policies
+	}
+	// the lookup only reports the identity policies in the data, Auth is nil
+	if raw, ok := secret.Data["identity_policies"].([]any); ok {
+		for _, policy := range raw {
+			name, ok := policy.(string)
+			if !ok {
+				return fmt.Errorf("unexpected identity policy %v in token lookup", policy)
+			}
+			info.IdentityPolicies = append(info.IdentityPolicies, name)
+		}
+	}
+	ttl, err := secret.TokenTTL()
+	if err != nil {
+		return err
+	}
+	info.TTL = int64(ttl.Seconds())
+	if info.Renewable, err = secret.TokenIsRenewable(); err != nil {
+		return err
+	}
+	info.DisplayName, _ = secret.Data["display_name"].(string)
+	info.EntityID, _ = secret.Data["entity_id"].(string)
+	info.Namespace, _ = secret.Data["namespace_path"].(string)
+	info.ExpireTime, _ = secret.Data["expire_time"].(string)
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(info)
+}